@@ -498,7 +498,7 @@ func TestTriggerChangeDetection(t *testing.T) {
 			s.Git().Checkout("main")
 			s.Git().Merge(testBranchName)
 			s.Git().Push("main")
-			s.Git().DeleteBranch(testBranchName)
+			s.Git().DeleteBranch(testBranchName, true)
 			s.Git().CheckoutNew(testBranchName)
 
 			tmcli := NewCLI(t, s.RootDir())
@@ -535,7 +535,7 @@ func TestTriggerChangeDetection(t *testing.T) {
 			s.Git().Checkout("main")
 			s.Git().Merge(testBranchName)
 			s.Git().Push("main")
-			s.Git().DeleteBranch(testBranchName)
+			s.Git().DeleteBranch(testBranchName, true)
 			s.Git().CheckoutNew(testBranchName)
 
 			tmcli := NewCLI(t, s.RootDir())
@@ -627,6 +627,6 @@ func setupLocalMainBranchBehindOriginMain(git *sandbox.Git, changeFiles func())
 	git.CommitAll("additional commit")
 	git.Push("main")
 	git.Checkout("temp")
-	git.DeleteBranch("main")
+	git.DeleteBranch("main", true)
 	git.CheckoutNew("main")
 }
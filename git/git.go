@@ -4,8 +4,10 @@
 package git
 
 import (
+	"bytes"
 	"errors"
 	"fmt"
+	"io"
 	"os"
 	"os/exec"
 	"path/filepath"
@@ -57,8 +59,13 @@ type (
 
 	// Ref is a git reference.
 	Ref struct {
-		Name     string
+		Name string
+		// CommitID is the id of the object the reference points to, which is
+		// the tag object for annotated tags.
 		CommitID string
+		// ObjectType is the type of the object the reference points to, eg.:
+		// commit or tag. It's only set by [Git.ForEachRef].
+		ObjectType string
 	}
 
 	// Remote is a git remote.
@@ -67,6 +74,9 @@ type (
 		Name string
 		// Branches are all the branches the remote reference has
 		Branches []string
+		// URL is the configured URL of the remote. It's only set by
+		// [Git.ListRemotes].
+		URL string
 	}
 
 	// LogLine is a log summary.
@@ -85,6 +95,201 @@ type (
 		stderr []byte // stderr of the failed command
 	}
 
+	// ConflictError is the error for operations stopped due to conflicts.
+	// The repository is left in the conflicted state, unless documented
+	// otherwise by the method.
+	ConflictError struct {
+		// Paths of the conflicting files relative to the repository root.
+		Paths []string
+
+		// Commit is the id of the commit that failed to be applied, if the
+		// operation applies multiple commits.
+		Commit string
+
+		err error // error of the failed command
+	}
+
+	// PatchError is the error for patches that don't apply, returned by
+	// [Git.ApplyPatch]. No file is changed if the patch doesn't apply.
+	PatchError struct {
+		// Paths of the files with rejected hunks as written in the patch.
+		Paths []string
+
+		err error // error of the failed command
+	}
+
+	// FetchOptions are the options for [Git.FetchWithOptions].
+	FetchOptions struct {
+		// Tags tells if all tags must be fetched from the remote.
+		Tags bool
+
+		// Prune removes the remote-tracking refs of branches that no longer
+		// exist on the remote.
+		Prune bool
+	}
+
+	// PushTagsOptions are the options for [Git.PushTagsWithOptions] and
+	// [Git.PushTagWithOptions].
+	PushTagsOptions struct {
+		// Force replaces the tags that already exist on the remote, which are
+		// rejected otherwise.
+		Force bool
+	}
+
+	// MergeOptions are the options for [Git.MergeWithOptions].
+	MergeOptions struct {
+		// FFOnly only allows fast-forward merges.
+		FFOnly bool
+
+		// NoFF always creates a merge commit, even if the merge could be
+		// resolved as a fast-forward.
+		NoFF bool
+	}
+
+	// CleanOptions are the options for [Git.Clean].
+	// Only untracked files are removed if no option is set.
+	CleanOptions struct {
+		// Dirs also removes untracked directories.
+		Dirs bool
+
+		// Ignored also removes the files ignored by the .gitignore rules.
+		Ignored bool
+	}
+
+	// CommitOptions are the options for [Git.CommitWithOptions].
+	// Empty fields use the values from the git configuration and the
+	// current time.
+	CommitOptions struct {
+		// AuthorName and AuthorEmail override the commit author.
+		AuthorName  string
+		AuthorEmail string
+
+		// Date overrides both the author and committer dates of the commit.
+		Date time.Time
+	}
+
+	// DefaultBranchOptions are the options for
+	// [Git.DefaultBranchWithOptions].
+	DefaultBranchOptions struct {
+		// Fallback is the branch returned if the default branch of the remote
+		// is unknown. If empty then [DefaultBranchFallback] is used.
+		Fallback string
+	}
+
+	// NotesOptions are the options for [Git.AddNoteWithOptions] and
+	// [Git.ReadNoteWithOptions].
+	NotesOptions struct {
+		// Ref is the notes ref, eg.: refs/notes/deployments
+		// If empty then [DefaultNotesRef] is used.
+		Ref string
+	}
+
+	// FileStatus is the status of a changed file in the working tree.
+	FileStatus struct {
+		// Path of the file relative to the repository root.
+		Path string
+
+		// OrigPath is the path the file was renamed or copied from, if any.
+		OrigPath string
+
+		// Staged is the status code of the file in the index and Unstaged
+		// is the status code of the file in the working tree, as documented
+		// in https://git-scm.com/docs/git-status#_short_format
+		// Unmodified is represented by '.' and untracked files have both
+		// codes set to '?'.
+		Staged   byte
+		Unstaged byte
+	}
+
+	// ConfigOptions are the options for [Git.ConfigGetWithOptions] and
+	// [Git.ConfigSetWithOptions].
+	ConfigOptions struct {
+		// Scope is the configuration file used. If not set then values are
+		// written to the repository configuration and read from all the
+		// configuration files, as git does by default.
+		Scope ConfigScope
+	}
+
+	// LogOptions are the options for [Git.Log].
+	LogOptions struct {
+		// Range is the revision range of the log, eg.: v1..HEAD
+		// If empty then the history of HEAD is returned.
+		Range string
+
+		// Paths limits the log to the commits touching the given paths.
+		Paths []string
+	}
+
+	// GraphOptions are the options for [Git.GraphLog].
+	GraphOptions struct {
+		// Revs are the revisions to start the graph from. If empty then the
+		// graph has the history of all the refs.
+		Revs []string
+
+		// MaxCount limits the number of commits of the graph if positive.
+		MaxCount int
+	}
+
+	// RevListOptions are the options for [Git.RevList].
+	RevListOptions struct {
+		// Range is the revision range of the commits, eg.: base..HEAD
+		// If empty then the commits reachable from HEAD are listed.
+		Range string
+
+		// MaxCount limits the number of listed commits if positive.
+		MaxCount int
+	}
+
+	// Commit is a commit of the log history.
+	Commit struct {
+		Hash    string
+		Author  string
+		Email   string
+		Date    time.Time
+		Subject string
+	}
+
+	// SignatureStatus is the signature of a commit checked by
+	// [Git.VerifyCommit].
+	SignatureStatus struct {
+		// Signed tells if the commit has a GPG or SSH signature.
+		Signed bool
+		// Valid tells if the signature was verified by git with a trusted
+		// key. It's always false for unsigned commits.
+		Valid bool
+		// Signer is the identity of the signer, if known, eg.: for SSH
+		// signatures it's the principal of the allowed signers file.
+		Signer string
+		// Key is the fingerprint of the signing key, if known.
+		Key string
+	}
+
+	// BlameLine is the attribution of a line of a file by [Git.Blame].
+	BlameLine struct {
+		// Line is the 1-based line number in the file.
+		Line int
+
+		// Commit is the id of the commit that last changed the line. It's
+		// the all-zero id if the line is not committed.
+		Commit string
+
+		// Committed tells if the line is committed. The author fields of
+		// lines not committed are set by git to "Not Committed Yet".
+		Committed bool
+
+		Author  string
+		Email   string
+		Content string
+	}
+
+	// CommitDetail is a commit with its patch, as returned by [Git.Show].
+	CommitDetail struct {
+		Commit
+
+		// Diff is the unified diff introduced by the commit.
+		Diff string
+	}
+
 	// CommitMetadata is metadata associated with a Git commit.
 	CommitMetadata struct {
 		Author  string
@@ -105,6 +310,73 @@ const (
 	// ErrDenyPorcelain is the error that tells if a porcelain method was called
 	// when AllowPorcelain is false.
 	ErrDenyPorcelain Error = "porcelain commands are not allowed by the configuration"
+
+	// ErrNoMergeBase is the error that tells if two commits have no common
+	// ancestor.
+	ErrNoMergeBase Error = "no merge base found"
+
+	// ErrFileNotFound is the error that tells if a file doesn't exist at a
+	// revision.
+	ErrFileNotFound Error = "file not found"
+
+	// ErrRemoteNotFound is the error that tells if a remote is not
+	// configured.
+	ErrRemoteNotFound Error = "remote not found"
+
+	// ErrNoteNotFound is the error that tells if a commit has no note.
+	ErrNoteNotFound Error = "note not found"
+)
+
+// DefaultBranchFallback is the branch returned by [Git.DefaultBranch] if the
+// default branch of the remote is unknown.
+const DefaultBranchFallback = "main"
+
+// DefaultNotesRef is the notes ref used if [NotesOptions.Ref] is not set.
+// It's always used explicitly, so the core.notesRef configuration is ignored.
+const DefaultNotesRef = "refs/notes/commits"
+
+// ConfigScope is the scope of a configuration value, which defines the
+// configuration file used.
+type ConfigScope string
+
+// Scopes supported by [ConfigOptions].
+const (
+	// LocalConfig is the repository configuration file.
+	LocalConfig ConfigScope = "local"
+
+	// GlobalConfig is the user configuration file.
+	GlobalConfig ConfigScope = "global"
+)
+
+// BranchFilter selects the branches listed by [Git.Branches].
+type BranchFilter int
+
+// Filters supported by [Git.Branches].
+const (
+	// AllBranches selects both local and remote branches.
+	AllBranches BranchFilter = iota
+
+	// LocalBranches selects only the local branches.
+	LocalBranches
+
+	// RemoteBranches selects only the remote-tracking branches.
+	RemoteBranches
+)
+
+// ResetMode is the mode of [Git.Reset].
+type ResetMode string
+
+// Modes supported by [Git.Reset].
+const (
+	// ResetSoft only moves HEAD, keeping the index and the working tree.
+	ResetSoft ResetMode = "soft"
+
+	// ResetMixed moves HEAD and resets the index but not the working tree.
+	ResetMixed ResetMode = "mixed"
+
+	// ResetHard moves HEAD and discards all changes of the index and of
+	// the working tree.
+	ResetHard ResetMode = "hard"
 )
 
 type remoteSorter []Remote
@@ -203,6 +475,9 @@ func (git *Git) Version() (string, error) {
 // Init initializes a git repository. If bare is true, it initializes a "bare
 // repository", in other words, a repository not intended for work but just
 // store revisions.
+// The initial branch is named defaultBranch, or the git configured default
+// if it's empty. On git versions without the --initial-branch flag the
+// unborn initial branch is renamed instead.
 // Beware: Init is a porcelain method.
 func (git *Git) Init(dir string, defaultBranch string, bare bool) error {
 	cfg := git.cfg()
@@ -210,17 +485,23 @@ func (git *Git) Init(dir string, defaultBranch string, bare bool) error {
 		return fmt.Errorf("Init: %w", ErrDenyPorcelain)
 	}
 
-	args := []string{
-		"-b", defaultBranch,
-		"--template=",
-	}
-
+	args := []string{"--template="}
 	if bare {
 		args = append(args, "--bare")
 	}
 
-	args = append(args, dir)
-	_, err := git.exec("init", args...)
+	renameBranch := false
+	var err error
+	if defaultBranch == "" {
+		_, err = git.exec("init", append(args, dir)...)
+	} else {
+		_, err = git.exec("init", append(args, "--initial-branch="+defaultBranch, dir)...)
+		var cmdErr *CmdError
+		if errors.As(err, &cmdErr) && strings.Contains(string(cmdErr.Stderr()), "initial-branch") {
+			_, err = git.exec("init", append(args, dir)...)
+			renameBranch = true
+		}
+	}
 	if err != nil {
 		return err
 	}
@@ -235,6 +516,13 @@ func (git *Git) Init(dir string, defaultBranch string, bare bool) error {
 
 	cfg.WorkingDir = dir
 
+	if renameBranch {
+		_, err = git.exec("symbolic-ref", "HEAD", "refs/heads/"+defaultBranch)
+		if err != nil {
+			return err
+		}
+	}
+
 	if cfg.Username != "" {
 		_, err = git.exec("config", "--local", "user.name", cfg.Username)
 		if err != nil {
@@ -258,6 +546,67 @@ func (git *Git) RemoteAdd(name string, url string) error {
 	return err
 }
 
+// RemoteRemove removes the named remote, with its remote-tracking branches
+// and configuration. It returns an error of kind [ErrRemoteNotFound] if the
+// remote is not configured.
+func (git *Git) RemoteRemove(name string) error {
+	_, err := git.exec("remote", "remove", name)
+	return remoteNotFound("RemoteRemove", name, err)
+}
+
+// RemoteRename renames the oldName remote to newName, also renaming its
+// remote-tracking branches. It returns an error of kind [ErrRemoteNotFound]
+// if the oldName remote is not configured.
+func (git *Git) RemoteRename(oldName, newName string) error {
+	_, err := git.exec("remote", "rename", oldName, newName)
+	return remoteNotFound("RemoteRename", oldName, err)
+}
+
+// remoteNotFound returns err wrapped as [ErrRemoteNotFound] if it's the
+// error of a git remote command for a missing remote.
+func remoteNotFound(action, name string, err error) error {
+	var cmdErr *CmdError
+	if errors.As(err, &cmdErr) && strings.Contains(string(cmdErr.Stderr()), "No such remote") {
+		return fmt.Errorf("%s: %w: %s", action, ErrRemoteNotFound, name)
+	}
+	return err
+}
+
+// DefaultBranch returns the short name of the default branch of remote, eg.:
+// main, as recorded by the refs/remotes/<remote>/HEAD symbolic ref, which is
+// set by clone and by `git remote set-head`. The [DefaultBranchFallback] is
+// returned if the symbolic ref is unset. It returns an error of kind
+// [ErrRemoteNotFound] if the remote is not configured.
+func (git *Git) DefaultBranch(remote string) (string, error) {
+	return git.DefaultBranchWithOptions(remote, DefaultBranchOptions{})
+}
+
+// DefaultBranchWithOptions is like [Git.DefaultBranch] but with the given
+// options.
+func (git *Git) DefaultBranchWithOptions(remote string, opts DefaultBranchOptions) (string, error) {
+	if _, err := git.exec("config", "--get", "remote."+remote+".url"); err != nil {
+		var cmdErr *CmdError
+		if errors.As(err, &cmdErr) && len(cmdErr.Stderr()) == 0 {
+			return "", fmt.Errorf("DefaultBranch: %w: %s", ErrRemoteNotFound, remote)
+		}
+		return "", err
+	}
+
+	head, err := git.exec("symbolic-ref", "--quiet", "--short", "refs/remotes/"+remote+"/HEAD")
+	if err != nil {
+		var cmdErr *CmdError
+		if !errors.As(err, &cmdErr) || len(cmdErr.Stderr()) != 0 {
+			return "", err
+		}
+		// the symbolic ref is unset.
+		if opts.Fallback == "" {
+			return DefaultBranchFallback, nil
+		}
+		return opts.Fallback, nil
+	}
+	return strings.TrimPrefix(head, remote+"/"), nil
+}
+
 // HasRemotes returns if a there are any remotes configured.
 func (git *Git) HasRemotes() (bool, error) {
 	res, err := git.exec("config", "--get-regexp", "remote\\.")
@@ -268,6 +617,64 @@ func (git *Git) HasRemotes() (bool, error) {
 	return res != "", nil
 }
 
+// RemoteURL returns the URL of the named remote verbatim as configured, so
+// url.<base>.insteadOf rewrites are not applied. It returns an error of kind
+// [ErrRemoteNotFound] if the remote is not configured.
+func (git *Git) RemoteURL(name string) (string, error) {
+	url, err := git.exec("config", "--get", "remote."+name+".url")
+	if err != nil {
+		var cmdErr *CmdError
+		if errors.As(err, &cmdErr) && len(cmdErr.Stderr()) == 0 {
+			return "", fmt.Errorf("RemoteURL: %w: %s", ErrRemoteNotFound, name)
+		}
+		return "", err
+	}
+	return url, nil
+}
+
+// ListRemotes returns all the configured remotes with their URLs and
+// respective branches. The result slice is ordered lexicographically by the
+// remote name.
+//
+// Returns an empty list if no remote is configured.
+func (git *Git) ListRemotes() ([]Remote, error) {
+	out, err := git.exec("config", "--get-regexp", `^remote\..*\.url$`)
+	if err != nil {
+		var cmdErr *CmdError
+		if errors.As(err, &cmdErr) && len(cmdErr.Stderr()) == 0 {
+			// no remote configured.
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	tracking, err := git.Remotes()
+	if err != nil {
+		return nil, err
+	}
+	branches := map[string][]string{}
+	for _, remote := range tracking {
+		branches[remote.Name] = remote.Branches
+	}
+
+	var remotes remoteSorter
+	for _, line := range strings.Split(out, "\n") {
+		key, url, ok := strings.Cut(line, " ")
+		if !ok {
+			return nil, fmt.Errorf("unexpected remote config %q", line)
+		}
+		name := strings.TrimSuffix(strings.TrimPrefix(key, "remote."), ".url")
+		remotes = append(remotes, Remote{
+			Name:     name,
+			Branches: branches[name],
+			URL:      url,
+		})
+	}
+
+	sort.Stable(remotes)
+	return remotes, nil
+}
+
 // Remotes returns a list of all configured remotes and their respective branches.
 // The result slice is ordered lexicographically by the remote name.
 //
@@ -351,7 +758,205 @@ func (git *Git) LogSummary(revs ...string) ([]LogLine, error) {
 	return logs, nil
 }
 
+// Log returns the commits of the history selected by opts in reverse
+// chronological order.
+// Beware: Log is a porcelain method.
+func (git *Git) Log(opts LogOptions) ([]Commit, error) {
+	if !git.cfg().AllowPorcelain {
+		return nil, fmt.Errorf("Log: %w", ErrDenyPorcelain)
+	}
+
+	args := []string{"--format=" + logFormat}
+	if opts.Range != "" {
+		args = append(args, opts.Range)
+	}
+	args = append(args, "--")
+	args = append(args, opts.Paths...)
+
+	out, err := git.exec("log", args...)
+	if err != nil {
+		return nil, err
+	}
+
+	commits := []Commit{}
+	for _, line := range strings.Split(out, "\n") {
+		if line == "" {
+			continue
+		}
+		commit, err := parseCommitLine(line)
+		if err != nil {
+			return nil, fmt.Errorf("Log: %w", err)
+		}
+		commits = append(commits, commit)
+	}
+	return commits, nil
+}
+
+// Show returns the metadata and the patch of the rev commit. The patch of
+// merge commits is the combined diff against all the parents, which only has
+// the files modified by the merge resolution.
+// Beware: Show is a porcelain method.
+func (git *Git) Show(rev string) (CommitDetail, error) {
+	if !git.cfg().AllowPorcelain {
+		return CommitDetail{}, fmt.Errorf("Show: %w", ErrDenyPorcelain)
+	}
+
+	// Same format as Log, with the patch after the record separator.
+	out, err := git.execRaw("show", "--cc", "--format="+logFormat+"%x1e", rev, "--")
+	if err != nil {
+		return CommitDetail{}, fmt.Errorf("Show: %w", err)
+	}
+
+	line, diff, ok := strings.Cut(string(out), "\x1e")
+	if !ok {
+		return CommitDetail{}, fmt.Errorf("Show: malformed output: %q", out)
+	}
+	commit, err := parseCommitLine(line)
+	if err != nil {
+		return CommitDetail{}, fmt.Errorf("Show: %w", err)
+	}
+	return CommitDetail{
+		Commit: commit,
+		Diff:   strings.TrimLeft(diff, "\n"),
+	}, nil
+}
+
+// logFormat is the pretty format of the commits parsed by parseCommitLine.
+// %H - commit hash
+// %an - author name
+// %ae - author email
+// %at - author time (unix)
+// %s - commit msg subject
+// The fields are separated by the ASCII unit separator.
+const logFormat = "%H%x1f%an%x1f%ae%x1f%at%x1f%s"
+
+func parseCommitLine(line string) (Commit, error) {
+	fields := strings.Split(line, "\x1f")
+	if len(fields) != 5 {
+		return Commit{}, fmt.Errorf("malformed log line: %q", line)
+	}
+	unixTime, err := strconv.ParseInt(fields[3], 10, 64)
+	if err != nil {
+		return Commit{}, fmt.Errorf("malformed commit date %q: %w", fields[3], err)
+	}
+	return Commit{
+		Hash:    fields[0],
+		Author:  fields[1],
+		Email:   fields[2],
+		Date:    time.Unix(unixTime, 0),
+		Subject: fields[4],
+	}, nil
+}
+
+// GraphLog returns the textual commit graph selected by opts, as printed by
+// `git log --graph --oneline --all`, with the refs decorating the commits.
+// Commits are in topological order and colors are disabled, so the output is
+// the same for the same history. It's meant for debugging.
+// Beware: GraphLog is a porcelain method.
+func (git *Git) GraphLog(opts GraphOptions) (string, error) {
+	if !git.cfg().AllowPorcelain {
+		return "", fmt.Errorf("GraphLog: %w", ErrDenyPorcelain)
+	}
+
+	args := []string{"--graph", "--oneline", "--decorate=short", "--topo-order", "--no-color"}
+	if opts.MaxCount > 0 {
+		args = append(args, fmt.Sprintf("--max-count=%d", opts.MaxCount))
+	}
+	if len(opts.Revs) == 0 {
+		args = append(args, "--all")
+	}
+	args = append(args, opts.Revs...)
+	args = append(args, "--")
+
+	return git.exec("log", args...)
+}
+
+// RevList returns the commit ids selected by opts in reverse chronological
+// order.
+func (git *Git) RevList(opts RevListOptions) ([]string, error) {
+	args := []string{}
+	if opts.MaxCount > 0 {
+		args = append(args, fmt.Sprintf("--max-count=%d", opts.MaxCount))
+	}
+	rev := opts.Range
+	if rev == "" {
+		rev = "HEAD"
+	}
+	args = append(args, rev, "--")
+
+	out, err := git.exec("rev-list", args...)
+	if err != nil {
+		return nil, err
+	}
+	return removeEmptyLines(strings.Split(out, "\n")), nil
+}
+
+// CountAhead returns the number of commits reachable from HEAD that are not
+// reachable from base.
+func (git *Git) CountAhead(base string) (int, error) {
+	out, err := git.exec("rev-list", "--count", base+"..HEAD", "--")
+	if err != nil {
+		return 0, err
+	}
+	count, err := strconv.Atoi(out)
+	if err != nil {
+		return 0, fmt.Errorf("CountAhead: malformed count %q: %w", out, err)
+	}
+	return count, nil
+}
+
+// CountCommits returns the number of commits reachable from HEAD, but not
+// from rev, that change the given path relative to the repository root.
+// Zero is returned if the path is unchanged since rev.
+func (git *Git) CountCommits(rev string, path string) (int, error) {
+	out, err := git.exec("rev-list", "--count", rev+"..HEAD", "--", path)
+	if err != nil {
+		return 0, err
+	}
+	count, err := strconv.Atoi(out)
+	if err != nil {
+		return 0, fmt.Errorf("CountCommits: malformed count %q: %w", out, err)
+	}
+	return count, nil
+}
+
+// CommitCount returns the number of commits reachable from rev, including rev
+// itself. Zero is returned if rev is HEAD or the branch HEAD points to and the
+// branch has no commits yet, as happens on the unborn initial branch of a new
+// repository. Any other missing rev is an error.
+func (git *Git) CommitCount(rev string) (int, error) {
+	out, err := git.exec("rev-list", "--count", rev, "--")
+	if err != nil {
+		if git.isUnbornRev(rev) {
+			return 0, nil
+		}
+		return 0, err
+	}
+	count, err := strconv.Atoi(out)
+	if err != nil {
+		return 0, fmt.Errorf("CommitCount: malformed count %q: %w", out, err)
+	}
+	return count, nil
+}
+
+// isUnbornRev tells if rev is HEAD, or the branch HEAD points to, and the
+// branch has no commits yet.
+func (git *Git) isUnbornRev(rev string) bool {
+	branch, err := git.exec("symbolic-ref", "--quiet", "HEAD")
+	if err != nil {
+		return false
+	}
+	if rev != "HEAD" && rev != branch && rev != strings.TrimPrefix(branch, "refs/heads/") {
+		return false
+	}
+	_, err = git.exec("rev-parse", "--verify", "--quiet", branch)
+	var cmdErr *CmdError
+	return errors.As(err, &cmdErr) && len(cmdErr.Stderr()) == 0
+}
+
 // Add files to current staged index.
+// The files are handled as git pathspecs, so patterns and magic signatures,
+// like :(exclude)path, are supported. See: https://git-scm.com/docs/gitglossary#Documentation/gitglossary.txt-aiddefpathspecapathspec
 // Beware: Add is a porcelain method.
 func (git *Git) Add(files ...string) error {
 	cfg := git.cfg()
@@ -362,8 +967,9 @@ func (git *Git) Add(files ...string) error {
 	log.Debug().
 		Str("action", "Add()").
 		Str("workingDir", cfg.WorkingDir).
+		Strs("pathspecs", files).
 		Msg("Add file to current staged index.")
-	_, err := git.exec("add", files...)
+	_, err := git.exec("add", append([]string{"--"}, files...)...)
 	return err
 }
 
@@ -378,10 +984,29 @@ func (git *Git) Clone(repoURL, dir string) error {
 	return err
 }
 
+// CloneBranch will clone the given repo inside the given dir and checkout the
+// given branch.
+// Beware: CloneBranch is a porcelain method.
+func (git *Git) CloneBranch(repoURL, branch, dir string) error {
+	cfg := git.cfg()
+	if !cfg.AllowPorcelain {
+		return fmt.Errorf("CloneBranch: %w", ErrDenyPorcelain)
+	}
+	_, err := git.exec("clone", "--branch", branch, repoURL, dir)
+	return err
+}
+
 // Commit the current staged changes.
 // The args are extra flags and/or arguments to git commit command line.
 // Beware: Commit is a porcelain method.
 func (git *Git) Commit(msg string, args ...string) error {
+	return git.CommitWithOptions(msg, CommitOptions{}, args...)
+}
+
+// CommitWithOptions is like [Git.Commit] but the author and date of the
+// commit are overridden by opts.
+// Beware: CommitWithOptions is a porcelain method.
+func (git *Git) CommitWithOptions(msg string, opts CommitOptions, args ...string) error {
 	cfg := git.cfg()
 
 	if !cfg.AllowPorcelain {
@@ -400,10 +1025,55 @@ func (git *Git) Commit(msg string, args ...string) error {
 
 	vargs = append(vargs, args...)
 
-	_, err := git.exec("commit", vargs...)
+	_, err := git.execEnv(opts.env(), "commit", vargs...)
+	return err
+}
+
+// Amend rewrites the last commit with the current staged changes. If msg is
+// empty then the message of the commit is kept. The original author is also
+// kept unless some of the opts are set, in which case the author and date are
+// reset as in [Git.CommitWithOptions].
+// Beware: Amend is a porcelain method.
+func (git *Git) Amend(msg string, opts CommitOptions) error {
+	if !git.cfg().AllowPorcelain {
+		return fmt.Errorf("Amend: %w", ErrDenyPorcelain)
+	}
+
+	args := []string{"--amend"}
+	if msg == "" {
+		args = append(args, "--no-edit")
+	} else {
+		args = append(args, "-m", msg)
+	}
+	if opts != (CommitOptions{}) {
+		args = append(args, "--reset-author")
+	}
+
+	log.Debug().
+		Str("action", "Amend()").
+		Str("workingDir", git.cfg().WorkingDir).
+		Msg("Amend last commit.")
+	_, err := git.execEnv(opts.env(), "commit", args...)
 	return err
 }
 
+// env returns the git environment variables overriding the commit author and
+// dates.
+func (opts CommitOptions) env() []string {
+	var env []string
+	if opts.AuthorName != "" {
+		env = append(env, "GIT_AUTHOR_NAME="+opts.AuthorName)
+	}
+	if opts.AuthorEmail != "" {
+		env = append(env, "GIT_AUTHOR_EMAIL="+opts.AuthorEmail)
+	}
+	if !opts.Date.IsZero() {
+		date := opts.Date.Format(time.RFC3339)
+		env = append(env, "GIT_AUTHOR_DATE="+date, "GIT_COMMITTER_DATE="+date)
+	}
+	return env
+}
+
 // RevParse parses the rev name and returns the commit id it points to.
 // The rev name follows the [git revisions](https://git-scm.com/docs/gitrevisions)
 // documentation.
@@ -411,6 +1081,18 @@ func (git *Git) RevParse(rev string) (string, error) {
 	return git.exec("rev-parse", rev)
 }
 
+// CurrentCommit returns the full commit id of HEAD.
+func (git *Git) CurrentCommit() (string, error) {
+	return git.exec("rev-parse", "--verify", "HEAD^{commit}")
+}
+
+// ShortHash returns the abbreviated commit id of the rev commit, using the
+// abbreviation length configured in git (core.abbrev), which by default is
+// the shortest unambiguous prefix of at least 7 characters.
+func (git *Git) ShortHash(rev string) (string, error) {
+	return git.exec("rev-parse", "--verify", "--short", rev+"^{commit}")
+}
+
 // FetchRemoteRev will fetch from the remote repo the commit id and ref name
 // for the given remote and reference. This will make use of the network
 // to fetch data from the remote configured on the git repo.
@@ -447,28 +1129,129 @@ func (git *Git) FetchRemoteRev(remote, ref string) (Ref, error) {
 }
 
 // MergeBase finds the common commit ancestor of commit1 and commit2.
+// It returns an error of kind [ErrNoMergeBase] if they have unrelated
+// histories.
 func (git *Git) MergeBase(commit1, commit2 string) (string, error) {
-	return git.exec("merge-base", commit1, commit2)
+	out, err := git.exec("merge-base", commit1, commit2)
+	if err != nil {
+		var cmdErr *CmdError
+		// git merge-base fails without any output if there is no common
+		// ancestor.
+		if errors.As(err, &cmdErr) && len(cmdErr.Stderr()) == 0 {
+			return "", fmt.Errorf("%w: %s and %s have unrelated histories",
+				ErrNoMergeBase, commit1, commit2)
+		}
+		return "", err
+	}
+	return out, nil
 }
 
-// Status returns the git status of the current branch.
+// Status returns the status of the changed files in the working tree,
+// including untracked files. It returns an empty list if the working tree
+// is clean.
 // Beware: Status is a porcelain method.
-func (git *Git) Status() (string, error) {
+func (git *Git) Status() ([]FileStatus, error) {
 	if !git.cfg().AllowPorcelain {
-		return "", fmt.Errorf("Status: %w", ErrDenyPorcelain)
+		return nil, fmt.Errorf("Status: %w", ErrDenyPorcelain)
 	}
 
-	return git.exec("status")
+	out, err := git.exec("status", "--porcelain=v2", "-z", "--untracked-files=all")
+	if err != nil {
+		return nil, err
+	}
+	return parseStatus(out)
 }
 
-// DiffTree compares the from and to commit ids and returns the differences. If
-// nameOnly is set then only the file names of changed files are show. If
-// recurse is set, then it walks into child trees as well. If
-// relative is set, then only show local changes of current dir.
-func (git *Git) DiffTree(from, to string, relative, nameOnly, recurse bool) (string, error) {
-	args := []string{from, to}
-
-	if relative {
+// HasStagedChanges tells if the index has changes not yet committed.
+// Beware: HasStagedChanges is a porcelain method.
+func (git *Git) HasStagedChanges() (bool, error) {
+	statuses, err := git.Status()
+	if err != nil {
+		return false, fmt.Errorf("HasStagedChanges: %w", err)
+	}
+	for _, status := range statuses {
+		if status.Staged != '.' && status.Staged != '?' {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// HasUnstagedChanges tells if tracked files in the working tree have changes
+// not yet staged. Untracked files are not considered changes.
+// Beware: HasUnstagedChanges is a porcelain method.
+func (git *Git) HasUnstagedChanges() (bool, error) {
+	statuses, err := git.Status()
+	if err != nil {
+		return false, fmt.Errorf("HasUnstagedChanges: %w", err)
+	}
+	for _, status := range statuses {
+		if status.Unstaged != '.' && status.Unstaged != '?' {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// parseStatus parses the output of `git status --porcelain=v2 -z`.
+// See: https://git-scm.com/docs/git-status#_porcelain_format_version_2
+func parseStatus(out string) ([]FileStatus, error) {
+	statuses := []FileStatus{}
+	entries := strings.Split(out, "\x00")
+	for i := 0; i < len(entries); i++ {
+		entry := entries[i]
+		if entry == "" {
+			continue
+		}
+
+		switch entry[0] {
+		case '?':
+			statuses = append(statuses, FileStatus{
+				Path:     entry[2:],
+				Staged:   '?',
+				Unstaged: '?',
+			})
+		case '!':
+			// ignored files are not requested.
+		case '1', '2', 'u':
+			// <type> <XY> <sub> <mH> <mI> <mW> <hH> <hI> [<Xscore>] <path>
+			// and unmerged entries have one more mode and hash.
+			nfields := map[byte]int{'1': 9, '2': 10, 'u': 11}[entry[0]]
+			fields := strings.SplitN(entry, " ", nfields)
+			if len(fields) != nfields || len(fields[1]) != 2 {
+				return nil, fmt.Errorf("malformed status entry: %q", entry)
+			}
+
+			status := FileStatus{
+				Path:     fields[nfields-1],
+				Staged:   fields[1][0],
+				Unstaged: fields[1][1],
+			}
+			if entry[0] == '2' {
+				// renamed and copied entries have the original path
+				// as the next NUL separated entry.
+				i++
+				if i >= len(entries) {
+					return nil, fmt.Errorf("malformed status entry: %q: missing original path", entry)
+				}
+				status.OrigPath = entries[i]
+			}
+			statuses = append(statuses, status)
+		default:
+			return nil, fmt.Errorf("unexpected status entry: %q", entry)
+		}
+	}
+	return statuses, nil
+}
+
+// DiffTree compares the from and to commit ids and returns the differences. If
+// nameOnly is set then only the file names of changed files are show. If
+// recurse is set, then it walks into child trees as well. If
+// relative is set, then only show local changes of current dir.
+func (git *Git) DiffTree(from, to string, relative, nameOnly, recurse bool) (string, error) {
+	args := []string{from, to}
+
+	if relative {
 		args = append(args, "--relative")
 	}
 
@@ -483,16 +1266,55 @@ func (git *Git) DiffTree(from, to string, relative, nameOnly, recurse bool) (str
 	return git.exec("diff-tree", args...)
 }
 
+// Diff compares the from and to revisions and returns the differences as a
+// patch. If nameOnly is set then only the names of the changed files are
+// returned, one per line. Renames are detected with --find-renames and the
+// paths are relative to the repository root.
+func (git *Git) Diff(from, to string, nameOnly bool) (string, error) {
+	args := []string{"-r", "--find-renames"}
+	if nameOnly {
+		args = append(args, "--name-only")
+	} else {
+		args = append(args, "-p")
+	}
+	args = append(args, from, to)
+	return git.exec("diff-tree", args...)
+}
+
 // DiffNames recursively walks the git tree objects computing the from and to
 // commit ids differences and return all the file names containing differences
-// relative to configuration WorkingDir.
+// relative to configuration WorkingDir, which are the repository relative
+// paths when WorkingDir is the repository root.
+// Renamed and copied files are detected with --find-renames and both the
+// original and the new names are reported.
 func (git *Git) DiffNames(from, to string) ([]string, error) {
-	diff, err := git.DiffTree(from, to, true, true, true)
+	out, err := git.exec("diff-tree", "--relative", "-r", "--find-renames",
+		"--name-status", "-z", from, to)
 	if err != nil {
 		return nil, fmt.Errorf("diff-tree: %w", err)
 	}
 
-	return removeEmptyLines(strings.Split(diff, "\n")), nil
+	// The output is a NUL separated list of entries in the form:
+	// <status> <path> or <status><score> <origpath> <path> for renames and
+	// copies.
+	names := []string{}
+	entries := strings.Split(out, "\x00")
+	for i := 0; i < len(entries); i++ {
+		status := entries[i]
+		if status == "" {
+			continue
+		}
+		npaths := 1
+		if status[0] == 'R' || status[0] == 'C' {
+			npaths = 2
+		}
+		if i+npaths >= len(entries) {
+			return nil, fmt.Errorf("diff-tree: malformed entry %q: missing path", status)
+		}
+		names = append(names, entries[i+1:i+1+npaths]...)
+		i += npaths
+	}
+	return names, nil
 }
 
 // NewBranch creates a new branch reference pointing to current HEAD.
@@ -511,8 +1333,15 @@ func (git *Git) NewBranch(name string) error {
 	return err
 }
 
-// DeleteBranch deletes the branch.
-func (git *Git) DeleteBranch(name string) error {
+// DeleteBranch deletes the local branch. If force is false then git refuses
+// to delete a branch not fully merged into its upstream, or into HEAD if it
+// has no upstream, and the refusal is returned as an error.
+// Beware: DeleteBranch is a porcelain method.
+func (git *Git) DeleteBranch(name string, force bool) error {
+	if !git.cfg().AllowPorcelain {
+		return fmt.Errorf("DeleteBranch: %w", ErrDenyPorcelain)
+	}
+
 	_, err := git.RevParse(name)
 	if err != nil {
 		return fmt.Errorf("branch \"%s\" doesn't exist", name)
@@ -522,8 +1351,213 @@ func (git *Git) DeleteBranch(name string) error {
 		Str("action", "DeleteBranch()").
 		Str("workingDir", git.cfg().WorkingDir).
 		Str("reference", name).
+		Bool("force", force).
 		Msg("Delete branch.")
-	_, err = git.exec("update-ref", "-d", "refs/heads/"+name)
+
+	flag := "--delete"
+	if force {
+		flag = "-D"
+	}
+	_, err = git.exec("branch", flag, name)
+	return err
+}
+
+// DeleteRemoteBranch deletes the branch from remote.
+// Beware: DeleteRemoteBranch is a porcelain method.
+func (git *Git) DeleteRemoteBranch(remote, name string) error {
+	if !git.cfg().AllowPorcelain {
+		return fmt.Errorf("DeleteRemoteBranch: %w", ErrDenyPorcelain)
+	}
+
+	log.Debug().
+		Str("action", "DeleteRemoteBranch()").
+		Str("workingDir", git.cfg().WorkingDir).
+		Str("remote", remote).
+		Str("reference", name).
+		Msg("Delete remote branch.")
+	_, err := git.exec("push", remote, "--delete", name)
+	return err
+}
+
+// SetUpstream configures the local branch to track remoteBranch of remote.
+// The remote branch must have been fetched.
+// Beware: SetUpstream is a porcelain method.
+func (git *Git) SetUpstream(branch, remote, remoteBranch string) error {
+	if !git.cfg().AllowPorcelain {
+		return fmt.Errorf("SetUpstream: %w", ErrDenyPorcelain)
+	}
+
+	log.Debug().
+		Str("action", "SetUpstream()").
+		Str("workingDir", git.cfg().WorkingDir).
+		Str("reference", branch).
+		Str("upstream", remote+"/"+remoteBranch).
+		Msg("Set branch upstream.")
+	_, err := git.exec("branch", "--set-upstream-to="+remote+"/"+remoteBranch, branch)
+	return err
+}
+
+// Branches returns the branch names selected by filter sorted by name, with
+// local branches coming first. Remote branches are returned as
+// <remote>/<branch>, eg.: origin/main, and the remote HEAD symbolic refs are
+// not listed.
+func (git *Git) Branches(filter BranchFilter) ([]string, error) {
+	var patterns []string
+	switch filter {
+	case LocalBranches:
+		patterns = []string{"refs/heads"}
+	case RemoteBranches:
+		patterns = []string{"refs/remotes"}
+	case AllBranches:
+		patterns = []string{"refs/heads", "refs/remotes"}
+	default:
+		return nil, fmt.Errorf("Branches: %w: unknown branch filter %d", ErrInvalidConfig, filter)
+	}
+
+	branches := []string{}
+	for _, pattern := range patterns {
+		out, err := git.exec("for-each-ref", "--format=%(refname:strip=2)", pattern)
+		if err != nil {
+			return nil, err
+		}
+		for _, name := range removeEmptyLines(strings.Split(out, "\n")) {
+			if pattern == "refs/remotes" && strings.HasSuffix(name, "/HEAD") {
+				continue
+			}
+			branches = append(branches, name)
+		}
+	}
+	return branches, nil
+}
+
+// BranchExists tells if there's a local or remote branch with the given name.
+// Remote branches must be named as <remote>/<branch>.
+func (git *Git) BranchExists(name string) bool {
+	branches, err := git.Branches(AllBranches)
+	if err != nil {
+		return false
+	}
+	for _, branch := range branches {
+		if branch == name {
+			return true
+		}
+	}
+	return false
+}
+
+// Tag creates a tag pointing to the current HEAD. If message is non-empty an
+// annotated tag is created, otherwise a lightweight tag is created.
+// Beware: Tag is a porcelain method.
+func (git *Git) Tag(name, message string) error {
+	if !git.cfg().AllowPorcelain {
+		return fmt.Errorf("Tag: %w", ErrDenyPorcelain)
+	}
+
+	args := []string{name}
+	if message != "" {
+		args = append(args, "-a", "-m", message)
+	}
+
+	log.Debug().
+		Str("action", "Tag()").
+		Str("workingDir", git.cfg().WorkingDir).
+		Str("tag", name).
+		Msg("Create tag.")
+	_, err := git.exec("tag", args...)
+	return err
+}
+
+// ListTags returns the name of all tags of the repository. The tags are sorted
+// by version semantics, eg.: v1.2.0 comes before v1.10.0, and names which are
+// not versions are lexically sorted.
+func (git *Git) ListTags() ([]string, error) {
+	out, err := git.exec("for-each-ref", "--sort=version:refname",
+		"--format=%(refname:strip=2)", "refs/tags")
+	if err != nil {
+		return nil, err
+	}
+	return removeEmptyLines(strings.Split(out, "\n")), nil
+}
+
+// TagsWithCommits returns the name of all tags of the repository mapped to
+// the commit they point to. Annotated tags are dereferenced, so the commit is
+// the one referred by the tag object and not the tag object itself, even for
+// tags of other annotated tags.
+func (git *Git) TagsWithCommits() (map[string]string, error) {
+	// %(*objectname) is the object referred by annotated tags and it's empty
+	// for lightweight tags, which point directly to a commit. It's peeled
+	// only once, so %(*objecttype) tells if it's yet another tag object.
+	out, err := git.exec("for-each-ref",
+		"--format=%(refname:strip=2) %(objectname) %(*objecttype) %(*objectname)", "refs/tags")
+	if err != nil {
+		return nil, fmt.Errorf("TagsWithCommits: %w", err)
+	}
+
+	tags := map[string]string{}
+	for _, line := range removeEmptyLines(strings.Split(out, "\n")) {
+		fields := strings.Fields(line)
+		switch len(fields) {
+		case 2:
+			tags[fields[0]] = fields[1]
+		case 4:
+			tags[fields[0]] = fields[3]
+			if fields[2] != "tag" {
+				continue
+			}
+			commit, err := git.exec("rev-parse", "--verify", "refs/tags/"+fields[0]+"^{commit}")
+			if err != nil {
+				return nil, fmt.Errorf("TagsWithCommits: dereferencing tag %s: %w", fields[0], err)
+			}
+			tags[fields[0]] = commit
+		default:
+			return nil, fmt.Errorf("TagsWithCommits: malformed tag line: %q", line)
+		}
+	}
+	return tags, nil
+}
+
+// ForEachRef returns the references matching the pattern, sorted by name.
+// The pattern is matched as done by `git for-each-ref`, so it's either a
+// glob, eg.: refs/tags/v*, or a prefix up to a slash, eg.: refs/heads
+// All the references are returned if the pattern is empty.
+func (git *Git) ForEachRef(pattern string) ([]Ref, error) {
+	args := []string{"--format=%(refname) %(objecttype) %(objectname)"}
+	if pattern != "" {
+		args = append(args, pattern)
+	}
+	out, err := git.exec("for-each-ref", args...)
+	if err != nil {
+		return nil, fmt.Errorf("ForEachRef: %w", err)
+	}
+
+	var refs []Ref
+	for _, line := range removeEmptyLines(strings.Split(out, "\n")) {
+		fields := strings.Fields(line)
+		if len(fields) != 3 {
+			return nil, fmt.Errorf("ForEachRef: malformed ref line: %q", line)
+		}
+		refs = append(refs, Ref{
+			Name:       fields[0],
+			ObjectType: fields[1],
+			CommitID:   fields[2],
+		})
+	}
+	return refs, nil
+}
+
+// DeleteTag deletes the tag.
+func (git *Git) DeleteTag(name string) error {
+	_, err := git.RevParse("refs/tags/" + name)
+	if err != nil {
+		return fmt.Errorf("tag \"%s\" doesn't exist", name)
+	}
+
+	log.Debug().
+		Str("action", "DeleteTag()").
+		Str("workingDir", git.cfg().WorkingDir).
+		Str("tag", name).
+		Msg("Delete tag.")
+	_, err = git.exec("update-ref", "-d", "refs/tags/"+name)
 	return err
 }
 
@@ -552,19 +1586,493 @@ func (git *Git) Checkout(rev string, create bool) error {
 	return err
 }
 
+// CheckoutOrphan creates and checks out the new orphan branch name, which has
+// no history. The index is emptied, so the first commit of the branch only
+// has the files added after the checkout. The files of the working tree
+// are kept as untracked files.
+// Beware: CheckoutOrphan is a porcelain method.
+func (git *Git) CheckoutOrphan(name string) error {
+	if !git.cfg().AllowPorcelain {
+		return fmt.Errorf("CheckoutOrphan: %w", ErrDenyPorcelain)
+	}
+
+	log.Debug().
+		Str("action", "CheckoutOrphan()").
+		Str("workingDir", git.cfg().WorkingDir).
+		Str("branch", name).
+		Msg("Checkout orphan branch.")
+
+	_, err := git.exec("checkout", "--orphan", name)
+	if err != nil {
+		return err
+	}
+	_, err = git.exec("rm", "-r", "--cached", "--quiet", "--ignore-unmatch", "--", ".")
+	return err
+}
+
+// CheckoutPaths restores the files at paths, both in the index and in the
+// working tree, as they are at rev. HEAD and the other files are unchanged.
+// Beware: CheckoutPaths is a porcelain method.
+func (git *Git) CheckoutPaths(rev string, paths ...string) error {
+	if !git.cfg().AllowPorcelain {
+		return fmt.Errorf("CheckoutPaths: %w", ErrDenyPorcelain)
+	}
+	if len(paths) == 0 {
+		return fmt.Errorf("CheckoutPaths: %w: no paths given", ErrInvalidConfig)
+	}
+
+	log.Debug().
+		Str("action", "CheckoutPaths()").
+		Str("workingDir", git.cfg().WorkingDir).
+		Str("reference", rev).
+		Strs("paths", paths).
+		Msg("Checkout paths.")
+	args := append([]string{rev, "--"}, paths...)
+	_, err := git.exec("checkout", args...)
+	return err
+}
+
+// Reset moves HEAD to rev, resetting the index and the working tree according
+// to mode.
+// Beware: Reset is a porcelain method.
+func (git *Git) Reset(mode ResetMode, rev string) error {
+	if !git.cfg().AllowPorcelain {
+		return fmt.Errorf("Reset: %w", ErrDenyPorcelain)
+	}
+
+	switch mode {
+	case ResetSoft, ResetMixed, ResetHard:
+	default:
+		return fmt.Errorf("Reset: %w: unknown reset mode %q", ErrInvalidConfig, mode)
+	}
+
+	log.Debug().
+		Str("action", "Reset()").
+		Str("workingDir", git.cfg().WorkingDir).
+		Str("mode", string(mode)).
+		Str("reference", rev).
+		Msg("Reset.")
+
+	_, err := git.exec("reset", "--"+string(mode), rev)
+	return err
+}
+
+// Clean removes the untracked files from the working tree. Untracked
+// directories and ignored files are only removed if requested with opts.
+// Beware: Clean is a porcelain method.
+func (git *Git) Clean(opts CleanOptions) error {
+	if !git.cfg().AllowPorcelain {
+		return fmt.Errorf("Clean: %w", ErrDenyPorcelain)
+	}
+
+	args := []string{"--force", "--quiet"}
+	if opts.Dirs {
+		args = append(args, "-d")
+	}
+	if opts.Ignored {
+		args = append(args, "-x")
+	}
+
+	log.Debug().
+		Str("action", "Clean()").
+		Str("workingDir", git.cfg().WorkingDir).
+		Bool("dirs", opts.Dirs).
+		Bool("ignored", opts.Ignored).
+		Msg("Clean untracked files.")
+
+	_, err := git.exec("clean", args...)
+	return err
+}
+
+// CherryPick applies the changes introduced by the rev commit on the current
+// branch, creating a new commit. If the changes conflict then a
+// [*ConflictError] is returned.
+// Beware: CherryPick is a porcelain method.
+func (git *Git) CherryPick(rev string) error {
+	if !git.cfg().AllowPorcelain {
+		return fmt.Errorf("CherryPick: %w", ErrDenyPorcelain)
+	}
+
+	log.Debug().
+		Str("action", "CherryPick()").
+		Str("workingDir", git.cfg().WorkingDir).
+		Str("reference", rev).
+		Msg("Cherry-pick commit.")
+
+	_, err := git.exec("cherry-pick", rev)
+	if err != nil {
+		return git.checkConflicts(err)
+	}
+	return nil
+}
+
+// ApplyPatch applies the unified diff patch to the working tree. The patch
+// is applied atomically, so if any hunk is rejected then no file is changed
+// and a [*PatchError] listing the failing files is returned.
+// Beware: ApplyPatch is a porcelain method.
+func (git *Git) ApplyPatch(patch []byte) error {
+	if !git.cfg().AllowPorcelain {
+		return fmt.Errorf("ApplyPatch: %w", ErrDenyPorcelain)
+	}
+
+	f, err := os.CreateTemp("", "terramate-patch-")
+	if err != nil {
+		return fmt.Errorf("ApplyPatch: creating patch file: %w", err)
+	}
+	defer func() { _ = os.Remove(f.Name()) }()
+
+	_, err = f.Write(patch)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("ApplyPatch: writing patch file: %w", err)
+	}
+
+	log.Debug().
+		Str("action", "ApplyPatch()").
+		Str("workingDir", git.cfg().WorkingDir).
+		Msg("Apply patch.")
+
+	_, err = git.exec("apply", f.Name())
+	if err == nil {
+		return nil
+	}
+
+	var cmdErr *CmdError
+	if !errors.As(err, &cmdErr) {
+		return err
+	}
+	paths := rejectedPatchPaths(string(cmdErr.Stderr()))
+	if len(paths) == 0 {
+		return err
+	}
+	return &PatchError{Paths: paths, err: err}
+}
+
+// rejectedPatchPaths returns the files reported as failing by the stderr of
+// `git apply`, in the order they are reported.
+func rejectedPatchPaths(stderr string) []string {
+	var paths []string
+	seen := map[string]bool{}
+	for _, line := range strings.Split(stderr, "\n") {
+		var path string
+		if msg, ok := strings.CutPrefix(line, "error: patch failed: "); ok {
+			// error: patch failed: <path>:<line>
+			if i := strings.LastIndex(msg, ":"); i > 0 {
+				path = msg[:i]
+			}
+		} else if msg, ok := strings.CutPrefix(line, "error: "); ok {
+			// error: <path>: <reason>
+			for _, reason := range []string{
+				": patch does not apply",
+				": No such file or directory",
+				": does not exist in index",
+				": already exists in working directory",
+			} {
+				if p, ok := strings.CutSuffix(msg, reason); ok {
+					path = p
+					break
+				}
+			}
+		}
+		if path != "" && !seen[path] {
+			seen[path] = true
+			paths = append(paths, path)
+		}
+	}
+	return paths
+}
+
+// Revert creates a new commit undoing the changes introduced by the rev
+// commit, using the default revert commit message. If the changes conflict
+// then a [*ConflictError] is returned.
+// Beware: Revert is a porcelain method.
+func (git *Git) Revert(rev string) error {
+	if !git.cfg().AllowPorcelain {
+		return fmt.Errorf("Revert: %w", ErrDenyPorcelain)
+	}
+
+	log.Debug().
+		Str("action", "Revert()").
+		Str("workingDir", git.cfg().WorkingDir).
+		Str("reference", rev).
+		Msg("Revert commit.")
+
+	_, err := git.exec("revert", "--no-edit", rev)
+	if err != nil {
+		return git.checkConflicts(err)
+	}
+	return nil
+}
+
+// Rebase reapplies the commits of the current branch on top of onto.
+// If a commit conflicts then the rebase is aborted, so the branch is left
+// unchanged, and a [*ConflictError] with the commit that failed to be applied
+// is returned.
+// Beware: Rebase is a porcelain method.
+func (git *Git) Rebase(onto string) error {
+	if !git.cfg().AllowPorcelain {
+		return fmt.Errorf("Rebase: %w", ErrDenyPorcelain)
+	}
+
+	log.Debug().
+		Str("action", "Rebase()").
+		Str("workingDir", git.cfg().WorkingDir).
+		Str("reference", onto).
+		Msg("Rebase.")
+
+	_, err := git.exec("rebase", onto)
+	if err == nil {
+		return nil
+	}
+
+	err = git.checkConflicts(err)
+	var conflictErr *ConflictError
+	if errors.As(err, &conflictErr) {
+		conflictErr.Commit, _ = git.exec("rev-parse", "--verify", "--quiet", "REBASE_HEAD")
+	}
+	if _, abortErr := git.exec("rebase", "--abort"); abortErr != nil {
+		log.Debug().Err(abortErr).Msg("aborting rebase")
+	}
+	return err
+}
+
+// VerifyCommit checks the signature of the rev commit with
+// `git verify-commit`, so the GPG or SSH signing configuration of the
+// repository, eg.: gpg.ssh.allowedSignersFile, is used to validate it.
+// Unsigned commits are not an error, they have a zero [SignatureStatus].
+// Beware: VerifyCommit is a porcelain method.
+func (git *Git) VerifyCommit(rev string) (SignatureStatus, error) {
+	if !git.cfg().AllowPorcelain {
+		return SignatureStatus{}, fmt.Errorf("VerifyCommit: %w", ErrDenyPorcelain)
+	}
+
+	commit, err := git.exec("rev-parse", "--verify", rev+"^{commit}")
+	if err != nil {
+		return SignatureStatus{}, fmt.Errorf("VerifyCommit: invalid revision %q: %w", rev, err)
+	}
+	object, err := git.exec("cat-file", "commit", commit)
+	if err != nil {
+		return SignatureStatus{}, fmt.Errorf("VerifyCommit: reading commit %s: %w", commit, err)
+	}
+	header, _, _ := strings.Cut(object, "\n\n")
+	if !strings.Contains(header, "\ngpgsig ") && !strings.Contains(header, "\ngpgsig-sha256 ") {
+		return SignatureStatus{}, nil
+	}
+
+	log.Debug().
+		Str("action", "VerifyCommit()").
+		Str("workingDir", git.cfg().WorkingDir).
+		Str("reference", rev).
+		Msg("Verify commit signature.")
+
+	status := SignatureStatus{Signed: true}
+	_, err = git.exec("verify-commit", commit)
+	status.Valid = err == nil
+
+	out, err := git.exec("log", "--max-count=1", "--format=%GS%x1f%GK", commit, "--")
+	if err != nil {
+		return SignatureStatus{}, fmt.Errorf("VerifyCommit: reading signer of %s: %w", commit, err)
+	}
+	status.Signer, status.Key, _ = strings.Cut(out, "\x1f")
+	return status, nil
+}
+
+// Blame returns the attribution of each line of the file at path, as it is
+// in the working tree, to the commit that last changed it.
+// Beware: Blame is a porcelain method.
+func (git *Git) Blame(path string) ([]BlameLine, error) {
+	if !git.cfg().AllowPorcelain {
+		return nil, fmt.Errorf("Blame: %w", ErrDenyPorcelain)
+	}
+
+	out, err := git.execRaw("blame", "--line-porcelain", "--", path)
+	if err != nil {
+		return nil, fmt.Errorf("Blame: %w", err)
+	}
+	return parseBlame(string(out))
+}
+
+// parseBlame parses the output of `git blame --line-porcelain`.
+// See: https://git-scm.com/docs/git-blame#_the_porcelain_format
+func parseBlame(out string) ([]BlameLine, error) {
+	lines := []BlameLine{}
+	var current *BlameLine
+	for _, entry := range strings.Split(out, "\n") {
+		switch {
+		case entry == "":
+			continue
+		case current == nil:
+			// <commit> <original line> <final line> [<group lines>]
+			fields := strings.Fields(entry)
+			if len(fields) < 3 {
+				return nil, fmt.Errorf("malformed blame header: %q", entry)
+			}
+			line, err := strconv.Atoi(fields[2])
+			if err != nil {
+				return nil, fmt.Errorf("malformed blame header: %q: %w", entry, err)
+			}
+			current = &BlameLine{
+				Line:      line,
+				Commit:    fields[0],
+				Committed: strings.Trim(fields[0], "0") != "",
+			}
+		case entry[0] == '\t':
+			current.Content = entry[1:]
+			lines = append(lines, *current)
+			current = nil
+		default:
+			key, value, _ := strings.Cut(entry, " ")
+			switch key {
+			case "author":
+				current.Author = value
+			case "author-mail":
+				current.Email = strings.TrimSuffix(strings.TrimPrefix(value, "<"), ">")
+			}
+		}
+	}
+	if current != nil {
+		return nil, fmt.Errorf("malformed blame output: missing content of line %d", current.Line)
+	}
+	return lines, nil
+}
+
+// checkConflicts returns a [*ConflictError] wrapping err if there are
+// conflicting files in the index, otherwise err is returned unchanged.
+func (git *Git) checkConflicts(err error) error {
+	out, diffErr := git.exec("diff", "--name-only", "--diff-filter=U", "-z")
+	if diffErr != nil {
+		return err
+	}
+	paths := removeEmptyLines(strings.Split(out, "\x00"))
+	if len(paths) == 0 {
+		return err
+	}
+	return &ConflictError{Paths: paths, err: err}
+}
+
+// WorktreeAdd checks out rev in a new worktree at path with a detached HEAD,
+// so no branch is created. A relative path is relative to the working dir.
+// The path can be an empty directory but it's an error if it already has any
+// file.
+// Beware: WorktreeAdd is a porcelain method.
+func (git *Git) WorktreeAdd(path, rev string) error {
+	if !git.cfg().AllowPorcelain {
+		return fmt.Errorf("WorktreeAdd: %w", ErrDenyPorcelain)
+	}
+
+	// git resolves a relative path from the working dir, not from ours.
+	dir := path
+	if !filepath.IsAbs(dir) {
+		dir = filepath.Join(git.cfg().WorkingDir, dir)
+	}
+	entries, err := os.ReadDir(dir)
+	if err == nil && len(entries) > 0 {
+		return fmt.Errorf("WorktreeAdd: target directory %q already exists and is not empty", path)
+	}
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("WorktreeAdd: checking target directory %q: %w", path, err)
+	}
+
+	log.Debug().
+		Str("action", "WorktreeAdd()").
+		Str("workingDir", git.cfg().WorkingDir).
+		Str("path", path).
+		Str("reference", rev).
+		Msg("Add worktree.")
+
+	_, err = git.exec("worktree", "add", "--detach", path, rev)
+	return err
+}
+
+// WorktreeRemove removes the worktree at path. It fails if the worktree has
+// uncommitted changes.
+// Beware: WorktreeRemove is a porcelain method.
+func (git *Git) WorktreeRemove(path string) error {
+	if !git.cfg().AllowPorcelain {
+		return fmt.Errorf("WorktreeRemove: %w", ErrDenyPorcelain)
+	}
+
+	log.Debug().
+		Str("action", "WorktreeRemove()").
+		Str("workingDir", git.cfg().WorkingDir).
+		Str("path", path).
+		Msg("Remove worktree.")
+
+	_, err := git.exec("worktree", "remove", path)
+	return err
+}
+
+// Stash saves the uncommitted changes of the tracked files and reverts the
+// working tree to HEAD. It does nothing if there are no changes to save.
+// Beware: Stash is a porcelain method.
+func (git *Git) Stash() error {
+	if !git.cfg().AllowPorcelain {
+		return fmt.Errorf("Stash: %w", ErrDenyPorcelain)
+	}
+
+	log.Debug().
+		Str("action", "Stash()").
+		Str("workingDir", git.cfg().WorkingDir).
+		Msg("Stash changes.")
+
+	// git stash push exits successfully without creating a stash entry if
+	// the working tree is clean.
+	_, err := git.exec("stash", "push")
+	return err
+}
+
+// StashPop applies the most recently stashed changes on the working tree and
+// drops them from the stash. If applying the changes conflicts then the git
+// error is returned and the stash entry is kept.
+// Beware: StashPop is a porcelain method.
+func (git *Git) StashPop() error {
+	if !git.cfg().AllowPorcelain {
+		return fmt.Errorf("StashPop: %w", ErrDenyPorcelain)
+	}
+
+	log.Debug().
+		Str("action", "StashPop()").
+		Str("workingDir", git.cfg().WorkingDir).
+		Msg("Pop stashed changes.")
+
+	_, err := git.exec("stash", "pop")
+	return err
+}
+
 // Merge branch into current branch using the non fast-forward strategy.
 // Beware: Merge is a porcelain method.
 func (git *Git) Merge(branch string) error {
+	return git.MergeWithOptions(branch, MergeOptions{NoFF: true})
+}
+
+// MergeWithOptions merges branch into current branch using the strategy
+// defined by opts. If no option is set then git decides if the merge is
+// fast-forward or not.
+// Beware: MergeWithOptions is a porcelain method.
+func (git *Git) MergeWithOptions(branch string, opts MergeOptions) error {
 	if !git.cfg().AllowPorcelain {
 		return fmt.Errorf("Merge: %w", ErrDenyPorcelain)
 	}
 
+	args := []string{}
+	switch {
+	case opts.FFOnly && opts.NoFF:
+		return fmt.Errorf("Merge: %w: FFOnly and NoFF are mutually exclusive", ErrInvalidConfig)
+	case opts.FFOnly:
+		args = append(args, "--ff-only")
+	case opts.NoFF:
+		args = append(args, "--no-ff")
+	}
+	args = append(args, branch)
+
 	log.Debug().
 		Str("action", "Merge()").
 		Str("workingDir", git.cfg().WorkingDir).
 		Str("reference", branch).
 		Msg("Merge.")
-	_, err := git.exec("merge", "--no-ff", branch)
+	_, err := git.exec("merge", args...)
 	return err
 }
 
@@ -577,6 +2085,87 @@ func (git *Git) Push(remote, branch string) error {
 	return err
 }
 
+// PushTags pushes all the local tags onto remote. Tags already existing on the
+// remote pointing to other objects are rejected.
+// Beware: PushTags is a porcelain method.
+func (git *Git) PushTags(remote string) error {
+	return git.PushTagsWithOptions(remote, PushTagsOptions{})
+}
+
+// PushTagsWithOptions is like [Git.PushTags] but with the given options.
+// Beware: PushTagsWithOptions is a porcelain method.
+func (git *Git) PushTagsWithOptions(remote string, opts PushTagsOptions) error {
+	return git.pushTags("PushTags", remote, opts, "--tags")
+}
+
+// PushTag pushes the local tag onto remote. The tag is rejected if it already
+// exists on the remote pointing to another object.
+// Beware: PushTag is a porcelain method.
+func (git *Git) PushTag(remote, tag string) error {
+	return git.PushTagWithOptions(remote, tag, PushTagsOptions{})
+}
+
+// PushTagWithOptions is like [Git.PushTag] but with the given options.
+// Beware: PushTagWithOptions is a porcelain method.
+func (git *Git) PushTagWithOptions(remote, tag string, opts PushTagsOptions) error {
+	return git.pushTags("PushTag", remote, opts, "refs/tags/"+tag)
+}
+
+func (git *Git) pushTags(action, remote string, opts PushTagsOptions, args ...string) error {
+	if !git.cfg().AllowPorcelain {
+		return fmt.Errorf("%s: %w", action, ErrDenyPorcelain)
+	}
+
+	args = append([]string{remote}, args...)
+	if opts.Force {
+		args = append(args, "--force")
+	}
+
+	log.Debug().
+		Str("action", action+"()").
+		Str("workingDir", git.cfg().WorkingDir).
+		Str("remote", remote).
+		Bool("force", opts.Force).
+		Msg("Push tags.")
+
+	_, err := git.exec("push", args...)
+	return err
+}
+
+// Fetch the refspecs from remote. If no refspec is given all branches are fetched
+// as configured for the remote.
+// Beware: Fetch is a porcelain method.
+func (git *Git) Fetch(remote string, refspecs ...string) error {
+	return git.FetchWithOptions(remote, FetchOptions{}, refspecs...)
+}
+
+// FetchWithOptions is like [Git.Fetch] but with the given options applied.
+// Beware: FetchWithOptions is a porcelain method.
+func (git *Git) FetchWithOptions(remote string, opts FetchOptions, refspecs ...string) error {
+	if !git.cfg().AllowPorcelain {
+		return fmt.Errorf("Fetch: %w", ErrDenyPorcelain)
+	}
+
+	args := []string{}
+	if opts.Tags {
+		args = append(args, "--tags")
+	}
+	if opts.Prune {
+		args = append(args, "--prune")
+	}
+	args = append(args, remote)
+	args = append(args, refspecs...)
+
+	log.Debug().
+		Str("action", "Fetch()").
+		Str("workingDir", git.cfg().WorkingDir).
+		Str("remote", remote).
+		Strs("refspecs", refspecs).
+		Msg("Fetch.")
+	_, err := git.exec("fetch", args...)
+	return err
+}
+
 // Pull changes from remote into branch
 func (git *Git) Pull(remote, branch string) error {
 	if !git.cfg().AllowPorcelain {
@@ -649,6 +2238,19 @@ func (git *Git) ListDirtyFiles() ([]string, []string, error) {
 	return untracked, uncommitted, nil
 }
 
+// LsFiles returns the paths of the tracked files, relative to the repository
+// root, sorted by name. If patterns are given then only the files matched by
+// the pathspec patterns are returned.
+func (git *Git) LsFiles(patterns ...string) ([]string, error) {
+	args := []string{"--cached", "--full-name", "-z", "--"}
+	args = append(args, patterns...)
+	out, err := git.exec("ls-files", args...)
+	if err != nil {
+		return nil, fmt.Errorf("LsFiles: %w", err)
+	}
+	return removeEmptyLines(strings.Split(out, "\x00")), nil
+}
+
 // ShowCommitMetadata returns common metadata associated with the given object.
 // An object name can be a commit SHA or a symbolic name, i.e. HEAD, branch-name, etc.
 func (git *Git) ShowCommitMetadata(objectName string) (*CommitMetadata, error) {
@@ -697,9 +2299,38 @@ func (git *Git) ShowCommitMetadata(objectName string) (*CommitMetadata, error) {
 	}, nil
 }
 
-// Root returns the git root directory.
+// Root returns the absolute path of the git root directory with all the
+// symlinks resolved.
 func (git *Git) Root() (string, error) {
-	return git.exec("rev-parse", "--show-toplevel")
+	root, err := git.exec("rev-parse", "--show-toplevel")
+	if err != nil {
+		return "", err
+	}
+	root, err = filepath.Abs(filepath.FromSlash(root))
+	if err != nil {
+		return "", fmt.Errorf("Root: %w", err)
+	}
+	root, err = filepath.EvalSymlinks(root)
+	if err != nil {
+		return "", fmt.Errorf("Root: evaluating symlinks: %w", err)
+	}
+	return root, nil
+}
+
+// IsInsideWorkTree tells if the configured working dir is inside the work tree
+// of a repository. It returns false if the working dir is not in a repository
+// or if it's inside the .git dir or a bare repository.
+func (git *Git) IsInsideWorkTree() (bool, error) {
+	out, err := git.exec("rev-parse", "--is-inside-work-tree")
+	if err != nil {
+		var cmdErr *CmdError
+		if errors.As(err, &cmdErr) &&
+			strings.Contains(string(cmdErr.Stderr()), "not a git repository") {
+			return false, nil
+		}
+		return false, err
+	}
+	return out == "true", nil
 }
 
 // IsRepository tell if the git wrapper setup is operating in a valid git
@@ -727,6 +2358,120 @@ func (git *Git) Exec(command string, args ...string) (string, error) {
 	return git.exec(command, args...)
 }
 
+// ShowFile returns the content of the file at path, relative to the
+// repository root, as it is at the given revision. It's the same as
+// `git show <rev>:<path>` but the content is read with plumbing commands.
+// It returns an error of kind [ErrFileNotFound] if the file doesn't exist at
+// the revision.
+func (git *Git) ShowFile(rev, path string) ([]byte, error) {
+	object, err := git.exec("rev-parse", "--verify", "--quiet", rev+":"+path)
+	if err != nil {
+		if _, err := git.exec("rev-parse", "--verify", "--quiet", rev+"^{tree}"); err != nil {
+			return nil, fmt.Errorf("ShowFile: invalid revision %q: %w", rev, err)
+		}
+		return nil, fmt.Errorf("ShowFile: %w: %s at %s", ErrFileNotFound, path, rev)
+	}
+	content, err := git.execRaw("cat-file", "blob", object)
+	if err != nil {
+		return nil, fmt.Errorf("ShowFile: reading %s at %s: %w", path, rev, err)
+	}
+	return content, nil
+}
+
+// AddNote attaches the note to the rev commit in the [DefaultNotesRef] notes
+// ref. It fails if the commit already has a note.
+// Beware: AddNote is a porcelain method.
+func (git *Git) AddNote(rev, note string) error {
+	return git.AddNoteWithOptions(rev, note, NotesOptions{})
+}
+
+// AddNoteWithOptions is like [Git.AddNote] but with the given options.
+// Beware: AddNoteWithOptions is a porcelain method.
+func (git *Git) AddNoteWithOptions(rev, note string, opts NotesOptions) error {
+	if !git.cfg().AllowPorcelain {
+		return fmt.Errorf("AddNote: %w", ErrDenyPorcelain)
+	}
+
+	log.Debug().
+		Str("action", "AddNote()").
+		Str("workingDir", git.cfg().WorkingDir).
+		Str("reference", rev).
+		Str("notesRef", opts.ref()).
+		Msg("Add note.")
+
+	_, err := git.exec("notes", "--ref="+opts.ref(), "add", "--message="+note, rev)
+	return err
+}
+
+// ReadNote returns the note of the rev commit in the [DefaultNotesRef] notes
+// ref. It returns an error of kind [ErrNoteNotFound] if the commit has no
+// note.
+// Beware: ReadNote is a porcelain method.
+func (git *Git) ReadNote(rev string) (string, error) {
+	return git.ReadNoteWithOptions(rev, NotesOptions{})
+}
+
+// ReadNoteWithOptions is like [Git.ReadNote] but with the given options.
+// Beware: ReadNoteWithOptions is a porcelain method.
+func (git *Git) ReadNoteWithOptions(rev string, opts NotesOptions) (string, error) {
+	if !git.cfg().AllowPorcelain {
+		return "", fmt.Errorf("ReadNote: %w", ErrDenyPorcelain)
+	}
+
+	note, err := git.exec("notes", "--ref="+opts.ref(), "show", rev)
+	if err != nil {
+		var cmdErr *CmdError
+		if errors.As(err, &cmdErr) && strings.Contains(string(cmdErr.Stderr()), "no note found") {
+			return "", fmt.Errorf("ReadNote: %w: %s in %s", ErrNoteNotFound, rev, opts.ref())
+		}
+		return "", err
+	}
+	return note, nil
+}
+
+func (opts NotesOptions) ref() string {
+	if opts.Ref == "" {
+		return DefaultNotesRef
+	}
+	return opts.Ref
+}
+
+// Archive writes a tar archive of the tree of rev to w. If paths are given
+// then only the files matching them, as pathspecs relative to the repository
+// root, are exported. The prefix, if any, is prepended to all the file names
+// of the archive and it's always handled as a directory, eg.: vendor/mod
+// Beware: Archive is a porcelain method.
+func (git *Git) Archive(rev, prefix string, w io.Writer, paths ...string) error {
+	if !git.cfg().AllowPorcelain {
+		return fmt.Errorf("Archive: %w", ErrDenyPorcelain)
+	}
+
+	args := []string{"--format=tar"}
+	if prefix != "" {
+		if !strings.HasSuffix(prefix, "/") {
+			prefix += "/"
+		}
+		args = append(args, "--prefix="+prefix)
+	}
+	args = append(args, rev, "--")
+	args = append(args, paths...)
+
+	log.Debug().
+		Str("action", "Archive()").
+		Str("workingDir", git.cfg().WorkingDir).
+		Str("reference", rev).
+		Strs("paths", paths).
+		Msg("Archive tree.")
+
+	// The archive is streamed to w, as it can be as big as the repository.
+	err := git.execStream(w, "archive", args...)
+	var cmdErr *CmdError
+	if err != nil && !errors.As(err, &cmdErr) {
+		return fmt.Errorf("Archive: writing archive of %s: %w", rev, err)
+	}
+	return err
+}
+
 // CurrentBranch returns the short branch name that HEAD points to.
 func (git *Git) CurrentBranch() (string, error) {
 	return git.exec("symbolic-ref", "--short", "HEAD")
@@ -746,6 +2491,56 @@ func (git *Git) URL(remote string) (string, error) {
 	return git.exec("remote", "get-url", remote)
 }
 
+// ConfigGet returns the value of the config key.
+func (git *Git) ConfigGet(key string) (string, error) {
+	return git.ConfigGetWithOptions(key, ConfigOptions{})
+}
+
+// ConfigGetWithOptions is like [Git.ConfigGet] but reads the value using the
+// given options.
+func (git *Git) ConfigGetWithOptions(key string, opts ConfigOptions) (string, error) {
+	args, err := configArgs(opts)
+	if err != nil {
+		return "", fmt.Errorf("ConfigGet: %w", err)
+	}
+	return git.exec("config", append(args, "--get", key)...)
+}
+
+// ConfigSet sets the config key to value in the repository configuration.
+func (git *Git) ConfigSet(key, value string) error {
+	return git.ConfigSetWithOptions(key, value, ConfigOptions{})
+}
+
+// ConfigSetWithOptions is like [Git.ConfigSet] but writes the value using
+// the given options.
+func (git *Git) ConfigSetWithOptions(key, value string, opts ConfigOptions) error {
+	args, err := configArgs(opts)
+	if err != nil {
+		return fmt.Errorf("ConfigSet: %w", err)
+	}
+
+	log.Debug().
+		Str("action", "ConfigSet()").
+		Str("workingDir", git.cfg().WorkingDir).
+		Str("scope", string(opts.Scope)).
+		Str("key", key).
+		Msg("Set config value.")
+
+	_, err = git.exec("config", append(args, key, value)...)
+	return err
+}
+
+func configArgs(opts ConfigOptions) ([]string, error) {
+	switch opts.Scope {
+	case "":
+		return nil, nil
+	case LocalConfig, GlobalConfig:
+		return []string{"--" + string(opts.Scope)}, nil
+	default:
+		return nil, fmt.Errorf("%w: unknown config scope %q", ErrInvalidConfig, opts.Scope)
+	}
+}
+
 // GetConfigValue returns the value mapped to given config key, or an error if they doesn't exist.
 func (git *Git) GetConfigValue(key string) (string, error) {
 	s, err := git.exec("config", key)
@@ -756,8 +2551,81 @@ func (git *Git) GetConfigValue(key string) (string, error) {
 }
 
 func (git *Git) exec(command string, args ...string) (string, error) {
+	return git.execEnv(nil, command, args...)
+}
+
+// execRaw is like exec but returns the stdout of the command unmodified.
+func (git *Git) execRaw(command string, args ...string) ([]byte, error) {
+	return git.execRawEnv(nil, command, args...)
+}
+
+// execEnv is like exec but the env variables are added to the environment
+// of the command.
+func (git *Git) execEnv(env []string, command string, args ...string) (string, error) {
+	stdout, err := git.execRawEnv(env, command, args...)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(string(stdout), "\n"), nil
+}
+
+func (git *Git) execRawEnv(env []string, command string, args ...string) ([]byte, error) {
+	cmd := git.newCmd(env, command, args...)
+	stdout, err := cmd.Output()
+	if err != nil {
+		stderr := []byte{}
+		var exitError *exec.ExitError
+		if errors.As(err, &exitError) {
+			stderr = exitError.Stderr
+		}
+		return nil, NewCmdError(cmd.String(), stdout, stderr)
+	}
+	return stdout, nil
+}
+
+// execStream is like exec but the stdout of the command is written to w
+// while the command runs, instead of being buffered. A failed command returns
+// a [CmdError] and other errors, like the ones writing to w, are returned as
+// they are.
+func (git *Git) execStream(w io.Writer, command string, args ...string) error {
+	cmd := git.newCmd(nil, command, args...)
+	var stderr bytes.Buffer
+	stdout := &recordingWriter{w: w}
+	cmd.Stdout = stdout
+	cmd.Stderr = &stderr
+	err := cmd.Run()
+
+	// A failure writing to w makes the command fail when writing to the
+	// closed pipe, so the write error takes precedence as it's the cause.
+	if stdout.err != nil {
+		return stdout.err
+	}
+	var exitError *exec.ExitError
+	if errors.As(err, &exitError) {
+		return NewCmdError(cmd.String(), nil, stderr.Bytes())
+	}
+	return err
+}
+
+// recordingWriter is a writer recording the first error writing to w.
+type recordingWriter struct {
+	w   io.Writer
+	err error
+}
+
+func (rw *recordingWriter) Write(p []byte) (int, error) {
+	n, err := rw.w.Write(p)
+	if err != nil && rw.err == nil {
+		rw.err = err
+	}
+	return n, err
+}
+
+// newCmd returns the command running the git command with args and the env
+// variables added to the environment configured for the wrapper.
+func (git *Git) newCmd(env []string, command string, args ...string) *exec.Cmd {
 	cfg := git.cfg()
-	cmd := exec.Cmd{
+	cmd := &exec.Cmd{
 		Path: cfg.ProgramPath,
 		Args: []string{cfg.ProgramPath},
 		Dir:  cfg.WorkingDir,
@@ -783,16 +2651,8 @@ func (git *Git) exec(command string, args ...string) (string, error) {
 		cmd.Env = append(cmd.Env, "GIT_ATTR_NOSYSTEM=1")
 	}
 
-	stdout, err := cmd.Output()
-	if err != nil {
-		stderr := []byte{}
-		var exitError *exec.ExitError
-		if errors.As(err, &exitError) {
-			stderr = exitError.Stderr
-		}
-		return "", NewCmdError(cmd.String(), stdout, stderr)
-	}
-	return strings.TrimRight(string(stdout), "\n"), nil
+	cmd.Env = append(cmd.Env, env...)
+	return cmd
 }
 
 func (git *Git) cfg() *Config { return &git.options.config }
@@ -823,6 +2683,25 @@ func (e *CmdError) Error() string {
 		e.cmd, string(e.stderr), string(e.stdout))
 }
 
+// Error string representation.
+func (e *ConflictError) Error() string {
+	if e.Commit != "" {
+		return fmt.Sprintf("conflicts applying commit %s in %s: %v",
+			e.Commit, strings.Join(e.Paths, ", "), e.err)
+	}
+	return fmt.Sprintf("conflicts in %s: %v", strings.Join(e.Paths, ", "), e.err)
+}
+
+// Unwrap returns the error of the failed command.
+func (e *ConflictError) Unwrap() error { return e.err }
+
+func (e *PatchError) Error() string {
+	return fmt.Sprintf("patch does not apply to %s: %v", strings.Join(e.Paths, ", "), e.err)
+}
+
+// Unwrap returns the error of the failed command.
+func (e *PatchError) Unwrap() error { return e.err }
+
 // ShortCommitID returns the short version of the commit ID.
 // If the reference doesn't have a valid commit id it returns empty.
 func (r Ref) ShortCommitID() string {
@@ -832,6 +2711,11 @@ func (r Ref) ShortCommitID() string {
 	return r.CommitID[0:8]
 }
 
+// IsUntracked tells if the file is not tracked by git.
+func (f FileStatus) IsUntracked() bool {
+	return f.Staged == '?'
+}
+
 // Command is the failed command.
 func (e *CmdError) Command() string { return e.cmd }
 
@@ -6,13 +6,21 @@
 package git_test
 
 import (
+	"archive/tar"
 	"errors"
 	"fmt"
+	"io"
+	"os"
+	"os/exec"
 	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
 	"github.com/madlambda/spells/assert"
 	"github.com/rs/zerolog"
 	"github.com/terramate-io/terramate/git"
@@ -151,23 +159,1238 @@ func TestGitOptions(t *testing.T) {
 	assert.EqualStrings(t, repodir2, gotRepoDir2)
 }
 
+func TestRootFromNestedDir(t *testing.T) {
+	t.Parallel()
+	s := sandbox.New(t)
+	g := s.Git()
+	nested := s.RootEntry().CreateDir("a/b/c")
+
+	assert.EqualStrings(t, s.RootDir(), g.Root())
+	assert.IsTrue(t, g.IsInsideWorkTree())
+
+	nestedGit := g.Unwrap().With().WorkingDir(nested.Path()).Wrapper()
+	root, err := nestedGit.Root()
+	assert.NoError(t, err)
+	assert.EqualStrings(t, s.RootDir(), root)
+
+	inside, err := nestedGit.IsInsideWorkTree()
+	assert.NoError(t, err)
+	assert.IsTrue(t, inside)
+
+	link := filepath.Join(t.TempDir(), "link")
+	assert.NoError(t, os.Symlink(s.RootDir(), link))
+	root, err = g.Unwrap().With().WorkingDir(filepath.Join(link, "a", "b")).Wrapper().Root()
+	assert.NoError(t, err)
+	assert.EqualStrings(t, s.RootDir(), root)
+
+	gitDir := g.Unwrap().With().WorkingDir(filepath.Join(s.RootDir(), ".git")).Wrapper()
+	inside, err = gitDir.IsInsideWorkTree()
+	assert.NoError(t, err)
+	assert.IsTrue(t, !inside)
+
+	norepo := g.Unwrap().With().WorkingDir(t.TempDir()).Wrapper()
+	inside, err = norepo.IsInsideWorkTree()
+	assert.NoError(t, err)
+	assert.IsTrue(t, !inside)
+	_, err = norepo.Root()
+	assert.Error(t, err)
+}
+
 func TestClone(t *testing.T) {
 	const (
 		filename = "test.txt"
 		content  = "test"
 	)
 	s := sandbox.New(t)
-	s.RootEntry().CreateFile(filename, content)
-	git := s.Git()
+	s.RootEntry().CreateFile(filename, content)
+	git := s.Git()
+
+	git.CommitAll("add file")
+
+	repoURL := "file://" + s.RootDir()
+	cloneDir := test.TempDir(t)
+	git.Clone(repoURL, cloneDir)
+
+	got := test.ReadFile(t, cloneDir, filename)
+	assert.EqualStrings(t, content, string(got))
+}
+
+func TestCloneBranch(t *testing.T) {
+	t.Parallel()
+	const (
+		filename = "test.txt"
+		content  = "test"
+		branch   = "feature"
+	)
+	s := sandbox.New(t)
+	git := s.Git()
+	git.CheckoutNew(branch)
+	s.RootEntry().CreateFile(filename, content)
+	git.CommitAll("add file")
+	git.Checkout("main")
+
+	repoURL := "file://" + s.RootDir()
+
+	cloneDir := test.TempDir(t)
+	git.Clone(repoURL, cloneDir)
+	cloned := sandbox.NewGit(t, cloneDir)
+	assert.EqualStrings(t, "main", cloned.CurrentBranch())
+	assertNoFile(t, filepath.Join(cloneDir, filename))
+
+	cloneDir = test.TempDir(t)
+	git.CloneBranch(repoURL, branch, cloneDir)
+	cloned = sandbox.NewGit(t, cloneDir)
+	assert.EqualStrings(t, branch, cloned.CurrentBranch())
+	got := test.ReadFile(t, cloneDir, filename)
+	assert.EqualStrings(t, content, string(got))
+}
+
+func TestTags(t *testing.T) {
+	t.Parallel()
+	s := sandbox.New(t)
+	git := s.Git()
+
+	assertEqualStringList(t, git.Tags(), nil)
+
+	git.Tag("v1.10.0", "")
+	git.Tag("v1.2.0", "release v1.2.0")
+	git.Tag("v1.9.0", "")
+	git.Tag("latest", "")
+
+	assertEqualStringList(t, git.Tags(), []string{"latest", "v1.2.0", "v1.9.0", "v1.10.0"})
+
+	annotated, err := git.Unwrap().Exec("cat-file", "-t", "v1.2.0")
+	assert.NoError(t, err)
+	assert.EqualStrings(t, "tag", annotated)
+
+	lightweight, err := git.Unwrap().Exec("cat-file", "-t", "v1.9.0")
+	assert.NoError(t, err)
+	assert.EqualStrings(t, "commit", lightweight)
+
+	git.DeleteTag("v1.9.0")
+	git.DeleteTag("latest")
+	assertEqualStringList(t, git.Tags(), []string{"v1.2.0", "v1.10.0"})
+
+	assert.Error(t, git.Unwrap().DeleteTag("latest"))
+}
+
+func TestForEachRef(t *testing.T) {
+	t.Parallel()
+	s := sandbox.New(t)
+	g := s.Git()
+
+	head := g.RevParse("HEAD")
+	g.Tag("v1.0.0", "")
+	g.Tag("v1.1.0", "annotated release")
+	g.Tag("latest", "")
+	assert.NoError(t, g.Unwrap().NewBranch("v2-branch"))
+	assert.NoError(t, g.Unwrap().NewBranch("feature"))
+
+	refs := g.ForEachRef("refs/tags/v*")
+	want := []git.Ref{
+		{Name: "refs/tags/v1.0.0", ObjectType: "commit", CommitID: head},
+		{Name: "refs/tags/v1.1.0", ObjectType: "tag", CommitID: g.RevParse("refs/tags/v1.1.0")},
+	}
+	if diff := cmp.Diff(refs, want); diff != "" {
+		t.Fatalf("unexpected refs (got-, want+):\n%s", diff)
+	}
+	assert.IsTrue(t, refs[1].CommitID != head, "annotated tag must point to the tag object")
+
+	var names []string
+	for _, ref := range g.ForEachRef("refs/heads") {
+		names = append(names, ref.Name)
+	}
+	assertEqualStringList(t, names, []string{
+		"refs/heads/feature", "refs/heads/main", "refs/heads/v2-branch",
+	})
+	assert.EqualInts(t, 0, len(g.ForEachRef("refs/tags/x*")))
+	assert.IsTrue(t, len(g.ForEachRef("")) > len(names)+len(want))
+}
+
+func TestTagsWithCommits(t *testing.T) {
+	t.Parallel()
+	s := sandbox.New(t)
+	g := s.Git()
+
+	assert.EqualInts(t, 0, len(g.TagsWithCommits()))
+
+	g.Tag("lightweight", "")
+	first := g.RevParse("HEAD")
+
+	s.RootEntry().CreateFile("file.txt", "data")
+	g.CommitAll("second")
+	g.Tag("annotated", "release")
+	second := g.RevParse("HEAD")
+
+	tagObject := g.RevParse("refs/tags/annotated")
+	assert.IsTrue(t, tagObject != second, "annotated tag must be a tag object")
+
+	// an annotated tag of the annotated tag must be dereferenced twice.
+	_, err := g.Unwrap().Exec("tag", "-a", "-m", "nested", "nested", "annotated")
+	assert.NoError(t, err)
+	nestedObject, err := g.Unwrap().Exec("rev-parse", "refs/tags/nested^{}")
+	assert.NoError(t, err)
+	assert.EqualStrings(t, second, nestedObject)
+	peeledOnce, err := g.Unwrap().Exec("for-each-ref", "--format=%(*objectname)", "refs/tags/nested")
+	assert.NoError(t, err)
+	assert.EqualStrings(t, tagObject, peeledOnce, "nested tag must point to the annotated tag object")
+
+	want := map[string]string{
+		"lightweight": first,
+		"annotated":   second,
+		"nested":      second,
+	}
+	if diff := cmp.Diff(g.TagsWithCommits(), want); diff != "" {
+		t.Fatalf("unexpected tags (got-, want+):\n%s", diff)
+	}
+}
+
+func TestFetch(t *testing.T) {
+	t.Parallel()
+	s := sandbox.New(t)
+	g := s.Git()
+	oldHead := g.RevParse("HEAD")
+
+	other := cloneRepo(t, g.BareRepoAbsPath())
+	test.WriteFile(t, other.BaseDir(), "other.txt", "other")
+	other.CommitAll("other commit")
+	other.Push("main")
+	other.CheckoutNew("feature")
+	other.Push("feature")
+	other.Tag("v1", "")
+	assert.NoError(t, other.Unwrap().Push("origin", "v1"))
+
+	g.Fetch("main")
+	assert.EqualStrings(t, other.RevParse("main"), g.RevParse("origin/main"))
+	assert.EqualStrings(t, oldHead, g.RevParse("HEAD"))
+	assertNoFile(t, filepath.Join(s.RootDir(), "other.txt"))
+
+	_, err := g.Unwrap().RevParse("origin/feature")
+	assert.Error(t, err, "feature branch must not be fetched yet")
+
+	g.FetchOn("origin")
+	assert.EqualStrings(t, other.RevParse("feature"), g.RevParse("origin/feature"))
+
+	assert.NoError(t, g.Unwrap().FetchWithOptions("origin", git.FetchOptions{Tags: true}))
+	assertEqualStringList(t, g.Tags(), []string{"v1"})
+}
+
+func TestMergeWithOptions(t *testing.T) {
+	t.Parallel()
+	s := sandbox.New(t)
+	g := s.Git()
+	root := s.RootEntry()
+
+	g.CheckoutNew("ff")
+	root.CreateFile("ff.txt", "ff")
+	g.CommitAll("ff commit")
+	ffCommit := g.RevParse("HEAD")
+
+	g.Checkout("main")
+	g.MergeFF("ff")
+	assert.EqualStrings(t, ffCommit, g.RevParse("HEAD"), "fast-forward must not create a commit")
+
+	g.CheckoutNew("noff")
+	root.CreateFile("noff.txt", "noff")
+	g.CommitAll("noff commit")
+	noffCommit := g.RevParse("HEAD")
+
+	g.Checkout("main")
+	g.MergeNoFF("noff")
+	assert.EqualStrings(t, noffCommit, g.RevParse("HEAD^2"), "merge commit second parent")
+	assert.EqualStrings(t, ffCommit, g.RevParse("HEAD^1"), "merge commit first parent")
+
+	// main diverged from ff, so it can't be fast-forwarded anymore.
+	g.Checkout("ff")
+	root.CreateFile("diverged.txt", "diverged")
+	g.CommitAll("diverged commit")
+	g.Checkout("main")
+	assert.Error(t, g.Unwrap().MergeWithOptions("ff", git.MergeOptions{FFOnly: true}))
+	assert.IsError(t, g.Unwrap().MergeWithOptions("ff", git.MergeOptions{FFOnly: true, NoFF: true}),
+		git.ErrInvalidConfig)
+}
+
+func TestBranches(t *testing.T) {
+	t.Parallel()
+	s := sandbox.New(t)
+	g := s.Git()
+
+	g.CheckoutNew("local-only")
+	g.CheckoutNew("pushed")
+	g.Push("pushed")
+	g.Checkout("main")
+
+	_, err := g.Unwrap().Exec("remote", "set-head", "origin", "main")
+	assert.NoError(t, err)
+
+	assertEqualStringList(t, g.Branches(git.LocalBranches),
+		[]string{"local-only", "main", "pushed"})
+	assertEqualStringList(t, g.Branches(git.RemoteBranches),
+		[]string{"origin/main", "origin/pushed"})
+	assertEqualStringList(t, g.Branches(git.AllBranches), []string{
+		"local-only", "main", "pushed", "origin/main", "origin/pushed",
+	})
+
+	assert.IsTrue(t, g.BranchExists("main"))
+	assert.IsTrue(t, g.BranchExists("local-only"))
+	assert.IsTrue(t, g.BranchExists("origin/pushed"))
+	assert.IsTrue(t, !g.BranchExists("origin/local-only"))
+	assert.IsTrue(t, !g.BranchExists("origin/HEAD"))
+	assert.IsTrue(t, !g.BranchExists("non-existent"))
+
+	_, err = g.Unwrap().Branches(git.BranchFilter(42))
+	assert.IsError(t, err, git.ErrInvalidConfig)
+}
+
+func TestMergeBase(t *testing.T) {
+	t.Parallel()
+	s := sandbox.New(t)
+	g := s.Git()
+	root := s.RootEntry()
+
+	root.CreateFile("fork.txt", "fork")
+	g.CommitAll("fork point")
+	forkCommit := g.RevParse("HEAD")
+
+	g.CheckoutNew("feature")
+	root.CreateFile("feature.txt", "feature")
+	g.CommitAll("feature commit")
+
+	g.Checkout("main")
+	root.CreateFile("main.txt", "main")
+	g.CommitAll("main commit")
+
+	assert.EqualStrings(t, forkCommit, g.MergeBase("main", "feature"))
+	assert.EqualStrings(t, forkCommit, g.MergeBase("feature", "main"))
+
+	_, err := g.Unwrap().Exec("checkout", "--orphan", "unrelated")
+	assert.NoError(t, err)
+	g.CommitAll("unrelated commit")
+
+	_, err = g.Unwrap().MergeBase("main", "unrelated")
+	assert.IsError(t, err, git.ErrNoMergeBase)
+
+	_, err = g.Unwrap().MergeBase("main", "non-existent")
+	assert.Error(t, err)
+	if errors.Is(err, git.ErrNoMergeBase) {
+		t.Fatalf("invalid revision reported as unrelated histories: %v", err)
+	}
+}
+
+func TestLog(t *testing.T) {
+	t.Parallel()
+	s := sandbox.New(t)
+	g := s.Git()
+	root := s.RootEntry()
+
+	root.CreateFile("stacks/foo/main.tf", "# v1")
+	g.CommitAll("release")
+	g.Tag("v1", "")
+
+	root.CreateFile("stacks/bar/main.tf", "# bar")
+	g.CommitAll("change bar")
+	barCommit := g.RevParse("HEAD")
+
+	root.CreateFile("stacks/foo/main.tf", "# foo")
+	g.CommitAll("change foo")
+	fooCommit := g.RevParse("HEAD")
+
+	root.CreateFile("stacks/foo/other.tf", "# other")
+	root.CreateFile("stacks/bar/other.tf", "# other")
+	g.CommitAll("change both")
+	bothCommit := g.RevParse("HEAD")
+
+	subjects := func(commits []git.Commit) []string {
+		res := []string{}
+		for _, c := range commits {
+			res = append(res, c.Subject)
+		}
+		return res
+	}
+
+	commits := g.Log(git.LogOptions{
+		Range: "v1..HEAD",
+		Paths: []string{"stacks/foo"},
+	})
+	assertEqualStringList(t, subjects(commits), []string{"change both", "change foo"})
+	assert.EqualStrings(t, bothCommit, commits[0].Hash)
+	assert.EqualStrings(t, fooCommit, commits[1].Hash)
+
+	commit := commits[0]
+	assert.EqualStrings(t, test.Username, commit.Author)
+	assert.EqualStrings(t, test.Email, commit.Email)
+	assert.IsTrue(t, !commit.Date.IsZero())
+
+	commits = g.Log(git.LogOptions{Range: "v1..HEAD"})
+	assertEqualStringList(t, subjects(commits),
+		[]string{"change both", "change foo", "change bar"})
+	assert.EqualStrings(t, barCommit, commits[2].Hash)
+
+	commits = g.Log(git.LogOptions{})
+	assertEqualStringList(t, subjects(commits),
+		[]string{
+			"change both", "change foo", "change bar", "release",
+			"add gitignore", "first commit",
+		})
+
+	commits = g.Log(git.LogOptions{Paths: []string{"non-existent"}})
+	assert.EqualInts(t, 0, len(commits))
+}
+
+func TestRevList(t *testing.T) {
+	t.Parallel()
+	s := sandbox.New(t)
+	g := s.Git()
+	root := s.RootEntry()
+
+	root.CreateFile("fork.txt", "fork")
+	g.CommitAll("fork point")
+	fork := g.RevParse("HEAD")
+
+	g.CheckoutNew("feature")
+	assert.EqualInts(t, 0, g.CountAhead("main"))
+
+	var want []string
+	for i := 0; i < 3; i++ {
+		root.CreateFile(fmt.Sprintf("file%d.txt", i), "content")
+		g.CommitAll(fmt.Sprintf("commit %d", i))
+		want = append([]string{g.RevParse("HEAD")}, want...)
+	}
+
+	g.Checkout("main")
+	root.CreateFile("main.txt", "main")
+	g.CommitAll("main commit")
+	g.Checkout("feature")
+
+	assert.EqualInts(t, 3, g.CountAhead("main"))
+	assert.EqualInts(t, 3, g.CountAhead(fork))
+	assertEqualStringList(t, g.RevList(git.RevListOptions{Range: "main..HEAD"}), want)
+	assertEqualStringList(t, g.RevList(git.RevListOptions{Range: "main..HEAD", MaxCount: 1}), want[:1])
+
+	all := g.RevList(git.RevListOptions{})
+	assert.EqualStrings(t, want[0], all[0])
+	assert.EqualStrings(t, fork, all[3])
+
+	_, err := g.Unwrap().CountAhead("non-existent")
+	assert.Error(t, err)
+}
+
+func TestCommitCount(t *testing.T) {
+	t.Parallel()
+	s := sandbox.New(t)
+	g := s.Git()
+	root := s.RootEntry()
+
+	base := g.CommitCount("HEAD")
+	assert.IsTrue(t, base > 0)
+
+	g.CheckoutNew("feature")
+	for i := 0; i < 3; i++ {
+		root.CreateFile(fmt.Sprintf("file%d.txt", i), "content")
+		g.CommitAll(fmt.Sprintf("commit %d", i))
+	}
+	assert.EqualInts(t, base+3, g.CommitCount("HEAD"))
+	assert.EqualInts(t, base+3, g.CommitCount("feature"))
+	assert.EqualInts(t, base, g.CommitCount("main"))
+	assert.EqualInts(t, base+1, g.CommitCount("HEAD~2"))
+
+	_, err := g.Unwrap().CommitCount("non-existent")
+	assert.Error(t, err)
+
+	empty := test.NewGitWrapper(t, test.EmptyRepo(t, false), []string{})
+	for _, rev := range []string{"HEAD", test.DefBranch, "refs/heads/" + test.DefBranch} {
+		count, err := empty.CommitCount(rev)
+		assert.NoError(t, err, "CommitCount(%s)", rev)
+		assert.EqualInts(t, 0, count, "CommitCount(%s)", rev)
+	}
+
+	// other revs are still missing on an empty repository.
+	for _, rev := range []string{"non-existent", "HEAD~1", "origin/" + test.DefBranch} {
+		_, err = empty.CommitCount(rev)
+		assert.Error(t, err, "CommitCount(%s)", rev)
+	}
+}
+
+func TestGraphLog(t *testing.T) {
+	t.Parallel()
+	s := sandbox.New(t)
+	g := s.Git()
+	root := s.RootEntry()
+
+	// origin/main has a single commit.
+	graph, err := g.Unwrap().GraphLog(git.GraphOptions{Revs: []string{"origin/main"}})
+	assert.NoError(t, err)
+	assert.EqualStrings(t, "* "+g.ShortHash("origin/main")+" (origin/main) first commit", graph)
+
+	g.CheckoutNew("feature")
+	root.CreateFile("feature.txt", "feature")
+	g.CommitAll("feature commit")
+	g.Checkout("main")
+	root.CreateFile("main.txt", "main")
+	g.CommitAll("main commit")
+	g.MergeNoFF("feature")
+
+	graph, err = g.Unwrap().GraphLog(git.GraphOptions{})
+	assert.NoError(t, err)
+	lines := strings.Split(graph, "\n")
+	assert.IsTrue(t, strings.HasPrefix(lines[0], "*   "+g.ShortHash("HEAD")+" (HEAD -> main)"), "graph:\n%s", graph)
+	assert.IsTrue(t, strings.Contains(graph, "(feature) feature commit"), "graph:\n%s", graph)
+	assert.IsTrue(t, strings.Contains(graph, "|/"), "graph:\n%s", graph)
+
+	again, err := g.Unwrap().GraphLog(git.GraphOptions{})
+	assert.NoError(t, err)
+	assert.EqualStrings(t, graph, again)
+
+	graph, err = g.Unwrap().GraphLog(git.GraphOptions{Revs: []string{"feature"}, MaxCount: 1})
+	assert.NoError(t, err)
+	assert.EqualStrings(t, "* "+g.ShortHash("feature")+" (feature) feature commit", graph)
+
+	g.DumpGraph(t)
+}
+
+func TestCountCommits(t *testing.T) {
+	t.Parallel()
+	s := sandbox.New(t)
+	g := s.Git()
+	root := s.RootEntry()
+
+	root.CreateFile("stacks/a/main.tf", "# v0")
+	g.CommitAll("add stack a")
+	g.Tag("v1", "release v1")
+
+	assert.EqualInts(t, 0, g.CountCommits("v1", "stacks/a"))
+
+	for i := 1; i <= 2; i++ {
+		root.CreateFile("stacks/a/main.tf", fmt.Sprintf("# v%d", i))
+		g.CommitAll(fmt.Sprintf("change stack a %d", i))
+	}
+	root.CreateFile("stacks/b/main.tf", "# b")
+	g.CommitAll("add stack b")
+
+	assert.EqualInts(t, 2, g.CountCommits("v1", "stacks/a"))
+	assert.EqualInts(t, 1, g.CountCommits("v1", "stacks/b"))
+	assert.EqualInts(t, 0, g.CountCommits("v1", "stacks/c"))
+	assert.EqualInts(t, 3, g.CountCommits("v1", "stacks"))
+
+	_, err := g.Unwrap().CountCommits("non-existent", "stacks/a")
+	assert.Error(t, err)
+}
+
+func TestDiff(t *testing.T) {
+	t.Parallel()
+	s := sandbox.New(t)
+	g := s.Git()
+	root := s.RootEntry()
+
+	root.CreateFile("dir/rename-me.txt", "content that is detected as a rename")
+	root.CreateFile("dir/sub/unchanged.txt", "unchanged")
+	root.CreateFile("modified.txt", "original")
+	root.CreateFile("deleted.txt", "deleted")
+	g.CommitAll("base")
+	base := g.RevParse("HEAD")
+
+	_, err := g.Unwrap().Exec("mv", "dir/rename-me.txt", "dir/sub/renamed file.txt")
+	assert.NoError(t, err)
+	root.CreateFile("modified.txt", "changed")
+	root.RemoveFile("deleted.txt")
+	root.CreateFile("added.txt", "added")
+	g.CommitAll("changes")
+	head := g.RevParse("HEAD")
+
+	assertEqualStringList(t, g.DiffNames(base, head), []string{
+		"added.txt",
+		"deleted.txt",
+		"dir/rename-me.txt",
+		"dir/sub/renamed file.txt",
+		"modified.txt",
+	})
+	assertEqualStringList(t, g.DiffNames(head, head), []string{})
+
+	names := g.Diff(base, head, true)
+	assertEqualStringList(t, strings.Split(names, "\n"), []string{
+		"added.txt",
+		"deleted.txt",
+		"dir/sub/renamed file.txt",
+		"modified.txt",
+	})
+
+	patch := g.Diff(base, head, false)
+	for _, want := range []string{
+		"rename from dir/rename-me.txt",
+		"rename to dir/sub/renamed file.txt",
+		"+changed",
+		"-original",
+	} {
+		assert.IsTrue(t, strings.Contains(patch, want),
+			"patch %q does not contain %q", patch, want)
+	}
+
+	dirGit := g.Unwrap().With().WorkingDir(filepath.Join(s.RootDir(), "dir")).Wrapper()
+	relnames, err := dirGit.DiffNames(base, head)
+	assert.NoError(t, err)
+	assertEqualStringList(t, relnames, []string{"rename-me.txt", "sub/renamed file.txt"})
+}
+
+func TestCheckoutPaths(t *testing.T) {
+	t.Parallel()
+	s := sandbox.New(t)
+	g := s.Git()
+	root := s.RootEntry()
+
+	restored := root.CreateFile("dir/restored.txt", "original")
+	kept := root.CreateFile("kept.txt", "original")
+	g.CommitAll("add files")
+	head := g.RevParse("HEAD")
+
+	restored.Write("changed")
+	kept.Write("changed")
+	g.Add("dir/restored.txt")
+
+	g.CheckoutPaths("HEAD", "dir/restored.txt")
+
+	got, err := os.ReadFile(restored.HostPath())
+	assert.NoError(t, err)
+	assert.EqualStrings(t, "original", string(got))
+	got, err = os.ReadFile(kept.HostPath())
+	assert.NoError(t, err)
+	assert.EqualStrings(t, "changed", string(got))
+
+	want := []git.FileStatus{
+		{Path: "kept.txt", Staged: '.', Unstaged: 'M'},
+	}
+	if diff := cmp.Diff(g.Status(), want); diff != "" {
+		t.Fatalf("unexpected status (got-, want+):\n%s", diff)
+	}
+	assert.EqualStrings(t, head, g.RevParse("HEAD"))
+
+	g.CheckoutPaths(head, "kept.txt")
+	assert.IsTrue(t, g.IsClean())
+	assert.Error(t, g.Unwrap().CheckoutPaths("HEAD"))
+}
+
+func TestCheckoutOrphan(t *testing.T) {
+	t.Parallel()
+	s := sandbox.New(t)
+	g := s.Git()
+	root := s.RootEntry()
+
+	root.CreateFile("main.txt", "main")
+	g.CommitAll("main commit")
+
+	g.CheckoutOrphan("gh-pages")
+	branch, err := g.Unwrap().CurrentBranch()
+	assert.NoError(t, err)
+	assert.EqualStrings(t, "gh-pages", branch)
+
+	root.CreateFile("index.html", "artifact")
+	g.Add("index.html")
+	g.Commit("publish artifacts")
+
+	assertEqualStringList(t, g.LsFiles(), []string{"index.html"})
+	parents, err := g.Unwrap().Exec("rev-list", "--parents", "-n", "1", "HEAD")
+	assert.NoError(t, err)
+	assertEqualStringList(t, strings.Fields(parents), []string{g.RevParse("HEAD")})
+}
+
+func TestClean(t *testing.T) {
+	t.Parallel()
+	s := sandbox.New(t)
+	g := s.Git()
+	root := s.RootEntry()
+
+	tracked := root.CreateFile("tracked.txt", "tracked")
+	root.CreateFile(".gitignore", "*.log\n")
+	g.CommitAll("base")
+
+	untracked := root.CreateFile("untracked.txt", "untracked")
+	untrackedDir := root.CreateFile("gen/file.txt", "generated")
+	ignored := root.CreateFile("debug.log", "ignored")
+
+	g.Clean(git.CleanOptions{})
+	assertNoFile(t, untracked.HostPath())
+	assert.IsTrue(t, fileExists(untrackedDir.HostPath()))
+	assert.IsTrue(t, fileExists(ignored.HostPath()))
+
+	g.Clean(git.CleanOptions{Dirs: true})
+	assertNoFile(t, filepath.Join(s.RootDir(), "gen"))
+	assert.IsTrue(t, fileExists(ignored.HostPath()))
+
+	g.Clean(git.CleanOptions{Ignored: true})
+	assertNoFile(t, ignored.HostPath())
+
+	assert.IsTrue(t, fileExists(tracked.HostPath()))
+	assert.IsTrue(t, g.IsClean())
+	assertEqualStringList(t, g.LsFiles(), []string{".gitignore", "README.md", "tracked.txt"})
+}
+
+func TestReset(t *testing.T) {
+	t.Parallel()
+	s := sandbox.New(t)
+	g := s.Git()
+	root := s.RootEntry()
+
+	root.CreateFile("staged.txt", "original")
+	root.CreateFile("unstaged.txt", "original")
+	g.CommitAll("base")
+	base := g.RevParse("HEAD")
+
+	root.CreateFile("staged.txt", "changed")
+	g.CommitAll("second commit")
+	second := g.RevParse("HEAD")
+
+	assert.NoError(t, g.Unwrap().Reset(git.ResetSoft, base))
+	assert.EqualStrings(t, base, g.RevParse("HEAD"))
+	want := []git.FileStatus{
+		{Path: "staged.txt", Staged: 'M', Unstaged: '.'},
+	}
+	if diff := cmp.Diff(g.Status(), want); diff != "" {
+		t.Fatalf("unexpected status after soft reset (got-, want+):\n%s", diff)
+	}
+
+	assert.NoError(t, g.Unwrap().Reset(git.ResetMixed, base))
+	want = []git.FileStatus{
+		{Path: "staged.txt", Staged: '.', Unstaged: 'M'},
+	}
+	if diff := cmp.Diff(g.Status(), want); diff != "" {
+		t.Fatalf("unexpected status after mixed reset (got-, want+):\n%s", diff)
+	}
+
+	g.Add("staged.txt")
+	root.CreateFile("unstaged.txt", "changed")
+	assert.IsTrue(t, !g.IsClean())
+
+	g.ResetHard("HEAD")
+	assert.IsTrue(t, g.IsClean())
+	assert.EqualStrings(t, base, g.RevParse("HEAD"))
+
+	g.ResetHard(second)
+	assert.IsTrue(t, g.IsClean())
+	assert.EqualStrings(t, second, g.RevParse("HEAD"))
+
+	err := g.Unwrap().Reset(git.ResetMode("keep-all"), "HEAD")
+	assert.IsError(t, err, git.ErrInvalidConfig)
+}
+
+func TestCherryPick(t *testing.T) {
+	t.Parallel()
+	s := sandbox.New(t)
+	g := s.Git()
+	root := s.RootEntry()
+
+	root.CreateFile("conflict.txt", "base")
+	g.CommitAll("base")
+
+	g.CheckoutNew("feature")
+	root.CreateFile("feature.txt", "feature")
+	g.CommitAll("add feature file")
+	featureCommit := g.RevParse("HEAD")
+
+	root.CreateFile("conflict.txt", "feature")
+	g.CommitAll("change conflict file on feature")
+	conflictCommit := g.RevParse("HEAD")
+
+	g.Checkout("main")
+	root.CreateFile("conflict.txt", "main")
+	g.CommitAll("change conflict file on main")
+
+	g.CherryPick(featureCommit)
+	got, err := os.ReadFile(filepath.Join(s.RootDir(), "feature.txt"))
+	assert.NoError(t, err)
+	assert.EqualStrings(t, "feature", string(got))
+	assertEqualStringList(t, g.DiffNames("HEAD~1", "HEAD"), []string{"feature.txt"})
+
+	err = g.TryCherryPick(conflictCommit)
+	var conflictErr *git.ConflictError
+	if !errors.As(err, &conflictErr) {
+		t.Fatalf("CherryPick() error = %v, want git.ConflictError", err)
+	}
+	assertEqualStringList(t, conflictErr.Paths, []string{"conflict.txt"})
+
+	var cmdErr *git.CmdError
+	assert.IsTrue(t, errors.As(err, &cmdErr), "conflict error doesn't wrap git.CmdError")
+}
+
+func TestApplyPatch(t *testing.T) {
+	t.Parallel()
+	s := sandbox.New(t)
+	g := s.Git()
+	root := s.RootEntry()
+
+	file := root.CreateFile("dir/file.txt", "line 1\nline 2\nline 3\n")
+	other := root.CreateFile("other.txt", "other\n")
+	g.CommitAll("add files")
+
+	file.Write("line 1\nchanged line 2\nline 3\n")
+	root.CreateFile("new.txt", "new\n")
+	g.Add("new.txt")
+	patch, err := g.Unwrap().Exec("diff", "HEAD")
+	assert.NoError(t, err)
+	g.ResetHard("HEAD")
+
+	g.ApplyPatch([]byte(patch + "\n"))
+	got, err := os.ReadFile(file.HostPath())
+	assert.NoError(t, err)
+	assert.EqualStrings(t, "line 1\nchanged line 2\nline 3\n", string(got))
+	got, err = os.ReadFile(filepath.Join(s.RootDir(), "new.txt"))
+	assert.NoError(t, err)
+	assert.EqualStrings(t, "new\n", string(got))
+
+	g.ResetHard("HEAD")
+	root.RemoveFile("new.txt")
+	file.Write("diverged\n")
+	rejected := patch + "\n" + `diff --git a/other.txt b/other.txt
+--- a/other.txt
++++ b/other.txt
+@@ -1 +1 @@
+-other
++patched
+`
+	err = g.TryApplyPatch([]byte(rejected))
+	var patchErr *git.PatchError
+	if !errors.As(err, &patchErr) {
+		t.Fatalf("ApplyPatch() error = %v, want git.PatchError", err)
+	}
+	assertEqualStringList(t, patchErr.Paths, []string{"dir/file.txt"})
+
+	// the patch is not partially applied.
+	got, err = os.ReadFile(other.HostPath())
+	assert.NoError(t, err)
+	assert.EqualStrings(t, "other\n", string(got))
+	assertNoFile(t, filepath.Join(s.RootDir(), "new.txt"))
+}
+
+func TestRevert(t *testing.T) {
+	t.Parallel()
+	s := sandbox.New(t)
+	g := s.Git()
+	root := s.RootEntry()
+
+	root.CreateFile("file.txt", "original")
+	g.CommitAll("add file")
+
+	root.CreateFile("file.txt", "changed")
+	root.CreateFile("new.txt", "new")
+	g.CommitAll("change files")
+	changeCommit := g.RevParse("HEAD")
+
+	g.Revert(changeCommit)
+	assert.EqualStrings(t, "original", g.ShowFile("HEAD", "file.txt"))
+	assertNoFile(t, filepath.Join(s.RootDir(), "new.txt"))
+	assert.IsTrue(t, g.IsClean())
+
+	commits := g.Log(git.LogOptions{Range: "HEAD~2..HEAD"})
+	if len(commits) != 2 {
+		t.Fatalf("Log() returned %d commits, want 2", len(commits))
+	}
+	assert.EqualStrings(t, `Revert "change files"`, commits[0].Subject)
+	assert.EqualStrings(t, changeCommit, commits[1].Hash)
+
+	root.CreateFile("file.txt", "conflict")
+	g.CommitAll("conflicting change")
+
+	err := g.TryRevert(changeCommit + "~1")
+	var conflictErr *git.ConflictError
+	if !errors.As(err, &conflictErr) {
+		t.Fatalf("Revert() error = %v, want git.ConflictError", err)
+	}
+	assertEqualStringList(t, conflictErr.Paths, []string{"file.txt"})
+}
+
+func TestWorktree(t *testing.T) {
+	t.Parallel()
+	s := sandbox.New(t)
+	g := s.Git()
+	root := s.RootEntry()
+
+	file := root.CreateFile("file.txt", "v1")
+	g.CommitAll("release v1")
+	g.Tag("v1", "")
+
+	file.Write("v2")
+	root.CreateFile("new.txt", "new")
+	g.CommitAll("release v2")
+
+	worktree := filepath.Join(t.TempDir(), "worktree")
+	g.WorktreeAdd(worktree, "v1")
+
+	got, err := os.ReadFile(filepath.Join(worktree, "file.txt"))
+	assert.NoError(t, err)
+	assert.EqualStrings(t, "v1", string(got))
+	assertNoFile(t, filepath.Join(worktree, "new.txt"))
+
+	// the main working tree is untouched.
+	got, err = os.ReadFile(filepath.Join(s.RootDir(), "file.txt"))
+	assert.NoError(t, err)
+	assert.EqualStrings(t, "v2", string(got))
+	assert.EqualStrings(t, "main", g.CurrentBranch())
+
+	g.WorktreeRemove(worktree)
+	assertNoFile(t, worktree)
+
+	emptyDir := t.TempDir()
+	g.WorktreeAdd(emptyDir, "v1")
+	g.WorktreeRemove(emptyDir)
+
+	nonEmptyDir := t.TempDir()
+	test.WriteFile(t, nonEmptyDir, "existent.txt", "")
+	assert.Error(t, g.Unwrap().WorktreeAdd(nonEmptyDir, "v1"))
+
+	// relative paths are relative to the working dir.
+	root.CreateFile("relative/existent.txt", "")
+	err = g.Unwrap().WorktreeAdd("relative", "v1")
+	assert.Error(t, err)
+	assert.IsTrue(t, strings.Contains(err.Error(), "not empty"), "unexpected error: %v", err)
+
+	g.WorktreeAdd("worktree", "v1")
+	got, err = os.ReadFile(filepath.Join(s.RootDir(), "worktree", "file.txt"))
+	assert.NoError(t, err)
+	assert.EqualStrings(t, "v1", string(got))
+	g.WorktreeRemove("worktree")
+	assertNoFile(t, filepath.Join(s.RootDir(), "worktree"))
+}
+
+func TestCommitWithOptions(t *testing.T) {
+	t.Parallel()
+	s := sandbox.New(t)
+	g := s.Git()
+
+	date := time.Date(2020, time.February, 29, 13, 14, 15, 0, time.UTC)
+	s.RootEntry().CreateFile("file.txt", "data")
+	g.Add("file.txt")
+	g.CommitWith("dated commit", git.CommitOptions{
+		AuthorName:  "Jane Doe",
+		AuthorEmail: "jane@example.com",
+		Date:        date,
+	})
+
+	commits := g.Log(git.LogOptions{Range: "HEAD~1..HEAD"})
+	if len(commits) != 1 {
+		t.Fatalf("Log() returned %d commits, want 1", len(commits))
+	}
+	got := commits[0]
+	assert.EqualStrings(t, "dated commit", got.Subject)
+	assert.EqualStrings(t, "Jane Doe", got.Author)
+	assert.EqualStrings(t, "jane@example.com", got.Email)
+	if !got.Date.Equal(date) {
+		t.Fatalf("commit date = %v, want %v", got.Date, date)
+	}
+
+	committerDate, err := g.Unwrap().Exec("show", "-s", "--format=%ct", "HEAD")
+	assert.NoError(t, err)
+	assert.EqualStrings(t, strconv.FormatInt(date.Unix(), 10), committerDate)
+
+	s.RootEntry().CreateFile("other.txt", "data")
+	g.Add("other.txt")
+	g.CommitWith("undated commit", git.CommitOptions{})
+	commits = g.Log(git.LogOptions{Range: "HEAD~1..HEAD"})
+	if commits[0].Date.Equal(date) {
+		t.Fatalf("commit without options must not reuse date %v", date)
+	}
+}
+
+func TestCurrentCommitAndShortHash(t *testing.T) {
+	t.Parallel()
+	s := sandbox.New(t)
+	g := s.Git()
+
+	s.RootEntry().CreateFile("file.txt", "data")
+	g.CommitAll("commit")
+
+	full := g.CurrentCommit()
+	assert.EqualStrings(t, g.RevParse("HEAD"), full)
+	assert.EqualInts(t, 40, len(full))
+
+	short := g.ShortHash("HEAD")
+	assert.IsTrue(t, len(short) >= 7 && len(short) < len(full),
+		"short hash %q has unexpected length", short)
+	assert.IsTrue(t, strings.HasPrefix(full, short),
+		"short hash %q is not a prefix of %q", short, full)
+
+	parent := g.ShortHash("HEAD~1")
+	assert.IsTrue(t, strings.HasPrefix(g.RevParse("HEAD~1"), parent),
+		"short hash %q is not a prefix of HEAD~1", parent)
+
+	_, err := g.Unwrap().ShortHash("non-existent")
+	assert.Error(t, err)
+}
+
+func TestInitWithBranch(t *testing.T) {
+	t.Parallel()
+
+	for _, branch := range []string{"main", "master", "trunk"} {
+		repodir := test.TempDir(t)
+		g := sandbox.NewGit(t, repodir)
+		g.InitWithBranch(branch)
+
+		test.WriteFile(t, repodir, "file.txt", "data")
+		g.Add("file.txt")
+		g.Commit("first commit")
+		assert.EqualStrings(t, branch, g.CurrentBranch())
+	}
+}
+
+func TestAmend(t *testing.T) {
+	t.Parallel()
+	s := sandbox.New(t)
+	g := s.Git()
+	root := s.RootEntry()
+
+	root.CreateFile("file.txt", "original")
+	g.CommitAll("original message")
+	original := g.Log(git.LogOptions{Range: "HEAD~1..HEAD"})[0]
+
+	root.CreateFile("file.txt", "amended")
+	g.Add("file.txt")
+	g.Amend("", git.CommitOptions{})
+
+	amended := g.Log(git.LogOptions{Range: "HEAD~1..HEAD"})[0]
+	assert.IsTrue(t, amended.Hash != original.Hash, "amend must change the commit hash")
+	assert.EqualStrings(t, "original message", amended.Subject)
+	assert.EqualStrings(t, original.Author, amended.Author)
+	assert.EqualStrings(t, "amended", g.ShowFile("HEAD", "file.txt"))
+	assert.EqualStrings(t, "add gitignore", g.Log(git.LogOptions{Range: "HEAD~2..HEAD~1"})[0].Subject)
+
+	date := time.Date(2021, time.June, 1, 10, 0, 0, 0, time.UTC)
+	g.Amend("new message", git.CommitOptions{AuthorName: "Jane Doe", Date: date})
+
+	reworded := g.Log(git.LogOptions{Range: "HEAD~1..HEAD"})[0]
+	assert.IsTrue(t, reworded.Hash != amended.Hash, "amend must change the commit hash")
+	assert.EqualStrings(t, "new message", reworded.Subject)
+	assert.EqualStrings(t, "Jane Doe", reworded.Author)
+	if !reworded.Date.Equal(date) {
+		t.Fatalf("commit date = %v, want %v", reworded.Date, date)
+	}
+	assert.EqualStrings(t, "amended", g.ShowFile("HEAD", "file.txt"))
+}
+
+func TestDeleteBranch(t *testing.T) {
+	t.Parallel()
+	s := sandbox.New(t)
+	g := s.Git()
+
+	g.CheckoutNew("merged")
+	g.Checkout("main")
+	g.DeleteBranch("merged", false)
+	assert.IsTrue(t, !g.BranchExists("merged"), "merged branch must be deleted")
+
+	g.CheckoutNew("unmerged")
+	s.RootEntry().CreateFile("file.txt", "data")
+	g.CommitAll("unmerged commit")
+	g.Checkout("main")
+
+	err := g.TryDeleteBranch("unmerged", false)
+	var cmdErr *git.CmdError
+	if !errors.As(err, &cmdErr) {
+		t.Fatalf("DeleteBranch(unmerged, false) error = %v, want git.CmdError", err)
+	}
+	assert.IsTrue(t, g.BranchExists("unmerged"), "unmerged branch must be kept")
+
+	g.DeleteBranch("unmerged", true)
+	assert.IsTrue(t, !g.BranchExists("unmerged"), "unmerged branch must be deleted")
+
+	assert.Error(t, g.TryDeleteBranch("unmerged", true))
+
+	g.CheckoutNew("remote-branch")
+	g.Push("remote-branch")
+	g.Checkout("main")
+	g.DeleteBranch("remote-branch", false)
+	assert.IsTrue(t, g.BranchExists("origin/remote-branch"), "remote branch must exist")
+
+	g.DeleteRemoteBranch("origin", "remote-branch")
+	assert.IsTrue(t, !g.BranchExists("origin/remote-branch"), "remote branch must be deleted")
+	_, err = g.Unwrap().Exec("ls-remote", "--exit-code", "--heads", "origin", "remote-branch")
+	assert.Error(t, err, "branch must be deleted from the remote")
+}
+
+func TestFetchPrune(t *testing.T) {
+	t.Parallel()
+	s := sandbox.New(t)
+	g := s.Git()
 
-	git.CommitAll("add file")
+	g.CheckoutNew("stale")
+	g.Push("stale")
+	g.Checkout("main")
+	g.DeleteBranch("stale", false)
 
-	repoURL := "file://" + s.RootDir()
-	cloneDir := test.TempDir(t)
-	git.Clone(repoURL, cloneDir)
+	bare := test.NewGitWrapper(t, g.BareRepoAbsPath(), []string{})
+	assert.NoError(t, bare.DeleteBranch("stale", true))
 
-	got := test.ReadFile(t, cloneDir, filename)
-	assert.EqualStrings(t, content, string(got))
+	g.Fetch()
+	assert.IsTrue(t, g.BranchExists("origin/stale"), "fetch without prune must keep stale refs")
+
+	g.FetchPrune()
+	assertEqualStringList(t, g.Branches(git.RemoteBranches), []string{"origin/main"})
+}
+
+func TestPushTags(t *testing.T) {
+	t.Parallel()
+	s := sandbox.New(t)
+	g := s.Git()
+	root := s.RootEntry()
+	bare := test.NewGitWrapper(t, g.BareRepoAbsPath(), []string{})
+
+	g.Tag("v1", "")
+	g.Tag("v2", "annotated")
+	g.PushTag("v1")
+
+	tags, err := bare.ListTags()
+	assert.NoError(t, err)
+	assertEqualStringList(t, tags, []string{"v1"})
+
+	g.PushTags()
+	tags, err = bare.ListTags()
+	assert.NoError(t, err)
+	assertEqualStringList(t, tags, []string{"v1", "v2"})
+	assert.EqualStrings(t, g.RevParse("v1"), revParse(t, bare, "v1"))
+
+	// moving a pushed tag is rejected unless forced.
+	root.CreateFile("new.txt", "new")
+	g.CommitAll("new commit")
+	g.DeleteTag("v1")
+	g.Tag("v1", "")
+	assert.Error(t, g.TryPushTag("v1"))
+	assert.Error(t, g.Unwrap().PushTags("origin"))
+	assert.IsTrue(t, g.RevParse("v1") != revParse(t, bare, "v1"))
+
+	assert.NoError(t, g.Unwrap().PushTagWithOptions("origin", "v1", git.PushTagsOptions{Force: true}))
+	assert.EqualStrings(t, g.RevParse("v1"), revParse(t, bare, "v1"))
+	assert.NoError(t, g.Unwrap().PushTagsWithOptions("origin", git.PushTagsOptions{Force: true}))
+}
+
+func revParse(t *testing.T, g *git.Git, rev string) string {
+	t.Helper()
+
+	out, err := g.RevParse(rev)
+	assert.NoError(t, err)
+	return out
+}
+
+func TestSetUpstream(t *testing.T) {
+	t.Parallel()
+	s := sandbox.New(t)
+	g := s.Git()
+
+	g.CheckoutNew("feature")
+	_, err := g.Unwrap().ConfigGet("branch.feature.remote")
+	assert.Error(t, err, "new branch must have no upstream")
+
+	g.SetUpstream("feature", "origin", "main")
+	assert.EqualStrings(t, "origin", g.ConfigGet("branch.feature.remote"))
+	assert.EqualStrings(t, "refs/heads/main", g.ConfigGet("branch.feature.merge"))
+
+	upstream, err := g.Unwrap().Exec("rev-parse", "--abbrev-ref", "feature@{upstream}")
+	assert.NoError(t, err)
+	assert.EqualStrings(t, "origin/main", upstream)
+
+	err = g.Unwrap().SetUpstream("feature", "origin", "non-existent")
+	assert.Error(t, err)
+}
+
+func TestRebase(t *testing.T) {
+	t.Parallel()
+	s := sandbox.New(t)
+	g := s.Git()
+	root := s.RootEntry()
+
+	root.CreateFile("conflict.txt", "base")
+	g.CommitAll("base")
+
+	g.CheckoutNew("feature")
+	root.CreateFile("feature1.txt", "feature")
+	g.CommitAll("feature 1")
+	root.CreateFile("feature2.txt", "feature")
+	g.CommitAll("feature 2")
+
+	g.Checkout("main")
+	root.CreateFile("main.txt", "main")
+	g.CommitAll("main change")
+
+	g.Checkout("feature")
+	g.Rebase("main")
+
+	subjects := []string{}
+	for _, c := range g.Log(git.LogOptions{Range: "HEAD~4..HEAD"}) {
+		subjects = append(subjects, c.Subject)
+	}
+	assertEqualStringList(t, subjects, []string{"feature 2", "feature 1", "main change", "base"})
+	assert.EqualStrings(t, g.RevParse("main"), g.MergeBase("main", "HEAD"))
+
+	// a merge commit would have more than one parent.
+	parents, err := g.Unwrap().Exec("rev-list", "--min-parents=2", "main..HEAD")
+	assert.NoError(t, err)
+	assert.EqualStrings(t, "", parents)
+
+	root.CreateFile("conflict.txt", "feature")
+	g.CommitAll("conflicting feature change")
+	conflictCommit := g.RevParse("HEAD")
+	g.Checkout("main")
+	root.CreateFile("conflict.txt", "main")
+	g.CommitAll("conflicting main change")
+	g.Checkout("feature")
+
+	err = g.TryRebase("main")
+	var conflictErr *git.ConflictError
+	if !errors.As(err, &conflictErr) {
+		t.Fatalf("Rebase() error = %v, want git.ConflictError", err)
+	}
+	assertEqualStringList(t, conflictErr.Paths, []string{"conflict.txt"})
+	assert.EqualStrings(t, conflictCommit, conflictErr.Commit)
+
+	// the rebase is aborted.
+	assert.EqualStrings(t, conflictCommit, g.RevParse("HEAD"))
+	assert.EqualStrings(t, "feature", g.CurrentBranch())
+	assert.IsTrue(t, g.IsClean())
+}
+
+func TestStash(t *testing.T) {
+	t.Parallel()
+	s := sandbox.New(t)
+	g := s.Git()
+	root := s.RootEntry()
+
+	file := root.CreateFile("file.txt", "original")
+	g.CommitAll("add file")
+
+	assertContent := func(want string) {
+		t.Helper()
+		got, err := os.ReadFile(file.HostPath())
+		assert.NoError(t, err)
+		assert.EqualStrings(t, want, string(got))
+	}
+
+	// stashing a clean tree is a no-op.
+	g.Stash()
+	assert.IsTrue(t, g.IsClean())
+
+	file.Write("modified")
+	assert.IsTrue(t, !g.IsClean())
+
+	g.Stash()
+	assert.IsTrue(t, g.IsClean())
+	assertContent("original")
+
+	g.StashPop()
+	assertContent("modified")
+	want := []git.FileStatus{
+		{Path: "file.txt", Staged: '.', Unstaged: 'M'},
+	}
+	if diff := cmp.Diff(g.Status(), want); diff != "" {
+		t.Fatalf("unexpected status (got-, want+):\n%s", diff)
+	}
+
+	g.Stash()
+	file.Write("conflicting")
+	g.CommitAll("conflicting change")
+
+	err := g.TryStashPop()
+	assert.Error(t, err)
+	var cmdErr *git.CmdError
+	assert.IsTrue(t, errors.As(err, &cmdErr), "error %v is not a git.CmdError", err)
+
+	// the stash entry is kept when popping fails.
+	stashes, err := g.Unwrap().Exec("stash", "list")
+	assert.NoError(t, err)
+	assert.IsTrue(t, stashes != "", "stash entry dropped after conflict")
 }
 
 func TestCurrentBranch(t *testing.T) {
@@ -352,6 +1575,107 @@ func TestListRemoteWithMultipleBranches(t *testing.T) {
 	assertEqualRemotes(t, got, want)
 }
 
+func TestRemoteURL(t *testing.T) {
+	t.Parallel()
+	s := sandbox.New(t)
+	g := s.Git()
+
+	const (
+		scpURL   = "git@github.com:terramate-io/terramate.git"
+		httpsURL = "https://gitlab.com/terramate-io/terramate.git"
+	)
+
+	g.RemoteAdd("github", scpURL)
+	g.RemoteAdd("gitlab", httpsURL)
+	g.RemoteAdd("with.dot", httpsURL)
+
+	// URL rewrites must not be applied to the configured URL.
+	_, err := g.Unwrap().Exec("config", "url.https://github.com/.insteadOf", "git@github.com:")
+	assert.NoError(t, err)
+
+	assert.EqualStrings(t, scpURL, g.RemoteURL("github"))
+	assert.EqualStrings(t, httpsURL, g.RemoteURL("gitlab"))
+	assert.EqualStrings(t, httpsURL, g.RemoteURL("with.dot"))
+
+	_, err = g.Unwrap().RemoteURL("non-existent")
+	assert.IsTrue(t, errors.Is(err, git.ErrRemoteNotFound), "got error %v", err)
+
+	assertEqualRemotes(t, g.ListRemotes(), []git.Remote{
+		{Name: "github", URL: scpURL},
+		{Name: "gitlab", URL: httpsURL},
+		{Name: "origin", Branches: []string{"main"}, URL: g.RemoteURL("origin")},
+		{Name: "with.dot", URL: httpsURL},
+	})
+
+	repodir := test.EmptyRepo(t, false)
+	remotes, err := test.NewGitWrapper(t, repodir, []string{}).ListRemotes()
+	assert.NoError(t, err)
+	assert.EqualInts(t, 0, len(remotes))
+}
+
+func TestDefaultBranch(t *testing.T) {
+	t.Parallel()
+	s := sandbox.New(t)
+	g := s.Git()
+
+	// the sandbox remote is added without setting its HEAD.
+	assert.EqualStrings(t, git.DefaultBranchFallback, g.DefaultBranch("origin"))
+	branch, err := g.Unwrap().DefaultBranchWithOptions("origin", git.DefaultBranchOptions{Fallback: "develop"})
+	assert.NoError(t, err)
+	assert.EqualStrings(t, "develop", branch)
+
+	_, err = g.Unwrap().Exec("remote", "set-head", "origin", "--auto")
+	assert.NoError(t, err)
+	assert.EqualStrings(t, "main", g.DefaultBranch("origin"))
+
+	otherDir := test.TempDir(t)
+	assert.NoError(t, test.NewGitWrapper(t, "", []string{}).Init(otherDir, "trunk", true))
+	g.RemoteAdd("other", otherDir)
+	g.PushOn("other", "trunk", "main")
+
+	_, err = g.Unwrap().Exec("remote", "set-head", "other", "--auto")
+	assert.NoError(t, err)
+	assert.EqualStrings(t, "trunk", g.DefaultBranch("other"))
+
+	_, err = g.Unwrap().Exec("remote", "set-head", "other", "--delete")
+	assert.NoError(t, err)
+	assert.EqualStrings(t, git.DefaultBranchFallback, g.DefaultBranch("other"))
+
+	_, err = g.Unwrap().DefaultBranch("non-existent")
+	assert.IsTrue(t, errors.Is(err, git.ErrRemoteNotFound), "got error %v", err)
+}
+
+func TestRemoteRenameAndRemove(t *testing.T) {
+	t.Parallel()
+	s := sandbox.New(t)
+	g := s.Git()
+
+	const mirrorURL = "https://gitlab.com/terramate-io/terramate.git"
+	originURL := g.RemoteURL("origin")
+
+	g.RemoteAdd("mirror", mirrorURL)
+	assertEqualRemotes(t, g.ListRemotes(), []git.Remote{
+		{Name: "mirror", URL: mirrorURL},
+		{Name: "origin", Branches: []string{"main"}, URL: originURL},
+	})
+
+	g.RemoteRename("origin", "upstream")
+	assertEqualRemotes(t, g.ListRemotes(), []git.Remote{
+		{Name: "mirror", URL: mirrorURL},
+		{Name: "upstream", Branches: []string{"main"}, URL: originURL},
+	})
+
+	g.RemoteRemove("mirror")
+	assertEqualRemotes(t, g.ListRemotes(), []git.Remote{
+		{Name: "upstream", Branches: []string{"main"}, URL: originURL},
+	})
+
+	err := g.Unwrap().RemoteRemove("mirror")
+	assert.IsTrue(t, errors.Is(err, git.ErrRemoteNotFound), "got error %v", err)
+	err = g.Unwrap().RemoteRename("origin", "other")
+	assert.IsTrue(t, errors.Is(err, git.ErrRemoteNotFound), "got error %v", err)
+}
+
 func TestShowMetadata(t *testing.T) {
 	type testcase struct {
 		name        string
@@ -462,6 +1786,470 @@ func TestGetConfigValue(t *testing.T) {
 	assert.Error(t, err, "git config: non-existing key")
 }
 
+func TestShowFile(t *testing.T) {
+	t.Parallel()
+	s := sandbox.New(t)
+	g := s.Git()
+	root := s.RootEntry()
+
+	const original = "original content\n\n"
+
+	file := root.CreateFile("dir/file.txt", original)
+	g.CommitAll("add file")
+	first := g.RevParse("HEAD")
+
+	file.Write("modified")
+	g.CommitAll("modify file")
+
+	assert.EqualStrings(t, original, g.ShowFile(first, "dir/file.txt"))
+	assert.EqualStrings(t, original, g.ShowFile("HEAD~1", "dir/file.txt"))
+	assert.EqualStrings(t, "modified", g.ShowFile("HEAD", "dir/file.txt"))
+
+	_, err := g.Unwrap().ShowFile("HEAD", "dir/non-existent.txt")
+	assert.IsError(t, err, git.ErrFileNotFound)
+
+	_, err = g.Unwrap().ShowFile("non-existent-rev", "dir/file.txt")
+	assert.Error(t, err)
+	if errors.Is(err, git.ErrFileNotFound) {
+		t.Fatalf("invalid revision reported as file not found: %v", err)
+	}
+}
+
+func TestConfigGetSet(t *testing.T) {
+	t.Parallel()
+	s := sandbox.New(t)
+	g := s.Git()
+
+	g.ConfigSet("user.name", "Another User")
+	g.ConfigSet("user.email", "another@example.com")
+	assert.EqualStrings(t, "Another User", g.ConfigGet("user.name"))
+	assert.EqualStrings(t, "another@example.com", g.ConfigGet("user.email"))
+
+	s.RootEntry().CreateFile("file.txt", "content")
+	g.CommitAll("commit with new identity")
+	metadata, err := g.Unwrap().ShowCommitMetadata("HEAD")
+	assert.NoError(t, err)
+	assert.EqualStrings(t, "Another User", metadata.Author)
+	assert.EqualStrings(t, "another@example.com", metadata.Email)
+
+	_, err = g.Unwrap().ConfigGet("terramate.nonexistent")
+	assert.Error(t, err)
+
+	err = g.Unwrap().ConfigSetWithOptions("user.name", "x", git.ConfigOptions{Scope: "worktree-all"})
+	assert.IsError(t, err, git.ErrInvalidConfig)
+}
+
+func TestConfigScopes(t *testing.T) {
+	t.Parallel()
+	repodir := mkOneCommitRepo(t)
+	globalConfig := filepath.Join(t.TempDir(), "gitconfig")
+
+	gw, err := git.WithConfig(git.Config{
+		WorkingDir:     repodir,
+		Env:            []string{"GIT_CONFIG_GLOBAL=" + globalConfig, "GIT_CONFIG_NOSYSTEM=1"},
+		AllowPorcelain: true,
+	})
+	assert.NoError(t, err)
+
+	global := git.ConfigOptions{Scope: git.GlobalConfig}
+	local := git.ConfigOptions{Scope: git.LocalConfig}
+
+	assert.NoError(t, gw.ConfigSetWithOptions("terramate.scope", "global", global))
+	got, err := gw.ConfigGet("terramate.scope")
+	assert.NoError(t, err)
+	assert.EqualStrings(t, "global", got)
+
+	_, err = gw.ConfigGetWithOptions("terramate.scope", local)
+	assert.Error(t, err)
+
+	assert.NoError(t, gw.ConfigSetWithOptions("terramate.scope", "local", local))
+	got, err = gw.ConfigGet("terramate.scope")
+	assert.NoError(t, err)
+	assert.EqualStrings(t, "local", got)
+
+	got, err = gw.ConfigGetWithOptions("terramate.scope", global)
+	assert.NoError(t, err)
+	assert.EqualStrings(t, "global", got)
+
+	content, err := os.ReadFile(globalConfig)
+	assert.NoError(t, err)
+	assert.IsTrue(t, strings.Contains(string(content), "scope = global"),
+		"global config %q has no value", content)
+}
+
+func TestHasStagedAndUnstagedChanges(t *testing.T) {
+	t.Parallel()
+	s := sandbox.New(t)
+	g := s.Git()
+	root := s.RootEntry()
+
+	root.CreateFile("staged.txt", "original")
+	root.CreateFile("unstaged.txt", "original")
+	g.CommitAll("add files")
+
+	root.CreateFile("untracked.txt", "untracked")
+	assert.IsTrue(t, !g.HasStagedChanges(), "untracked files are not staged changes")
+	assert.IsTrue(t, !g.HasUnstagedChanges(), "untracked files are not unstaged changes")
+
+	root.CreateFile("staged.txt", "changed")
+	g.Add("staged.txt")
+	assert.IsTrue(t, g.HasStagedChanges())
+	assert.IsTrue(t, !g.HasUnstagedChanges())
+
+	root.CreateFile("unstaged.txt", "changed")
+	assert.IsTrue(t, g.HasStagedChanges())
+	assert.IsTrue(t, g.HasUnstagedChanges())
+
+	root.RemoveFile("untracked.txt")
+	g.CommitAll("commit all")
+	assert.IsTrue(t, !g.HasStagedChanges())
+	assert.IsTrue(t, !g.HasUnstagedChanges())
+
+	root.RemoveFile("staged.txt")
+	assert.IsTrue(t, !g.HasStagedChanges())
+	assert.IsTrue(t, g.HasUnstagedChanges(), "deleted files are unstaged changes")
+}
+
+func TestAddPathspec(t *testing.T) {
+	t.Parallel()
+	s := sandbox.New(t)
+	g := s.Git()
+	root := s.RootEntry()
+
+	root.CreateFile("a.txt", "a")
+	root.CreateFile("dir/b.txt", "b")
+	root.CreateFile("dir/secret.txt", "secret")
+	root.CreateFile("-dash.txt", "dash")
+
+	g.AddPathspec(".", ":(exclude)dir/secret.txt", ":(exclude)-dash.txt")
+	want := []git.FileStatus{
+		{Path: "a.txt", Staged: 'A', Unstaged: '.'},
+		{Path: "dir/b.txt", Staged: 'A', Unstaged: '.'},
+		{Path: "-dash.txt", Staged: '?', Unstaged: '?'},
+		{Path: "dir/secret.txt", Staged: '?', Unstaged: '?'},
+	}
+	if diff := cmp.Diff(g.Status(), want); diff != "" {
+		t.Fatalf("unexpected status (got-, want+):\n%s", diff)
+	}
+
+	// paths starting with a dash are not handled as flags.
+	g.Add("-dash.txt")
+	want[2].Staged, want[2].Unstaged = 'A', '.'
+	want = []git.FileStatus{want[2], want[0], want[1], want[3]}
+	if diff := cmp.Diff(g.Status(), want); diff != "" {
+		t.Fatalf("unexpected status (got-, want+):\n%s", diff)
+	}
+}
+
+func TestShow(t *testing.T) {
+	t.Parallel()
+	s := sandbox.New(t)
+	g := s.Git()
+	root := s.RootEntry()
+
+	root.CreateFile("conflict.txt", "base\n")
+	g.CommitAll("base")
+
+	root.CreateFile("file.txt", "content\n")
+	g.CommitAll("add file")
+
+	detail := g.Show("HEAD")
+	assert.EqualStrings(t, "add file", detail.Subject)
+	assert.EqualStrings(t, g.RevParse("HEAD"), detail.Hash)
+	assert.EqualStrings(t, test.Username, detail.Author)
+	assert.IsTrue(t, strings.HasPrefix(detail.Diff, "diff --git a/file.txt b/file.txt\n"),
+		"unexpected diff:\n%s", detail.Diff)
+	assert.IsTrue(t, strings.Contains(detail.Diff, "+content\n"), "unexpected diff:\n%s", detail.Diff)
+
+	g.CheckoutNew("feature")
+	root.CreateFile("conflict.txt", "feature\n")
+	g.CommitAll("feature change")
+	g.Checkout("main")
+	root.CreateFile("conflict.txt", "main\n")
+	g.CommitAll("main change")
+
+	err := g.Unwrap().Merge("feature")
+	assert.Error(t, err, "merge must conflict")
+	root.CreateFile("conflict.txt", "resolved\n")
+	g.Add("conflict.txt")
+	g.Commit("merge feature")
+
+	merge := g.Show("HEAD")
+	assert.EqualStrings(t, "merge feature", merge.Subject)
+	assert.IsTrue(t, strings.HasPrefix(merge.Diff, "diff --cc conflict.txt\n"),
+		"unexpected merge diff:\n%s", merge.Diff)
+	assert.IsTrue(t, strings.Contains(merge.Diff, "resolved"), "unexpected merge diff:\n%s", merge.Diff)
+
+	_, err = g.Unwrap().Show("non-existent")
+	assert.Error(t, err)
+}
+
+func TestNotes(t *testing.T) {
+	t.Parallel()
+	s := sandbox.New(t)
+	g := s.Git()
+
+	_, err := g.Unwrap().ReadNote("HEAD")
+	assert.IsTrue(t, errors.Is(err, git.ErrNoteNotFound), "got error %v", err)
+
+	g.AddNote("HEAD", "deployed: prod\nby: ci")
+	assert.EqualStrings(t, "deployed: prod\nby: ci", g.ReadNote("HEAD"))
+	assert.Error(t, g.Unwrap().AddNote("HEAD", "again"))
+
+	custom := git.NotesOptions{Ref: "refs/notes/deployments"}
+	_, err = g.Unwrap().ReadNoteWithOptions("HEAD", custom)
+	assert.IsTrue(t, errors.Is(err, git.ErrNoteNotFound), "got error %v", err)
+	assert.NoError(t, g.Unwrap().AddNoteWithOptions("HEAD", "custom", custom))
+
+	note, err := g.Unwrap().ReadNoteWithOptions("HEAD", custom)
+	assert.NoError(t, err)
+	assert.EqualStrings(t, "custom", note)
+	assert.EqualStrings(t, "deployed: prod\nby: ci", g.ReadNote("HEAD"))
+}
+
+func TestArchive(t *testing.T) {
+	t.Parallel()
+	s := sandbox.New(t)
+	g := s.Git()
+	root := s.RootEntry()
+
+	root.CreateFile("modules/vpc/main.tf", "# vpc")
+	root.CreateFile("modules/vpc/sub/variables.tf", "# vars")
+	root.CreateFile("modules/dns/main.tf", "# dns")
+	g.CommitAll("add modules")
+	rev := g.RevParse("HEAD")
+
+	root.CreateFile("modules/vpc/outputs.tf", "# not in rev")
+	g.CommitAll("add outputs")
+
+	assertEqualStringList(t, tarFiles(t, g.Archive(rev, "")), []string{
+		".gitignore",
+		"README.md",
+		"modules/dns/main.tf",
+		"modules/vpc/main.tf",
+		"modules/vpc/sub/variables.tf",
+	})
+	assertEqualStringList(t, tarFiles(t, g.Archive(rev, "vendor/vpc", "modules/vpc")), []string{
+		"vendor/vpc/modules/vpc/main.tf",
+		"vendor/vpc/modules/vpc/sub/variables.tf",
+	})
+	assertEqualStringList(t, tarFiles(t, g.Archive("HEAD", "", "modules/vpc")), []string{
+		"modules/vpc/main.tf",
+		"modules/vpc/outputs.tf",
+		"modules/vpc/sub/variables.tf",
+	})
+
+	var out strings.Builder
+	err := g.Unwrap().Archive("non-existent", "", &out)
+	var cmdErr *git.CmdError
+	assert.IsTrue(t, errors.As(err, &cmdErr), "got error %v", err)
+
+	// the archive is bigger than the pipe buffers, so git fails writing it
+	// after w fails.
+	root.CreateFile("big.bin", strings.Repeat("0123456789abcdef", 1<<16))
+	g.CommitAll("add big file")
+	errWrite := errors.New("disk full")
+	err = g.Unwrap().Archive("HEAD", "", failingWriter{err: errWrite})
+	assert.IsTrue(t, errors.Is(err, errWrite), "got error %v", err)
+}
+
+// failingWriter is a writer failing all the writes with err.
+type failingWriter struct {
+	err error
+}
+
+func (w failingWriter) Write([]byte) (int, error) {
+	return 0, w.err
+}
+
+// tarFiles returns the sorted names of the regular files of the tar archive.
+func tarFiles(t *testing.T, archivePath string) []string {
+	t.Helper()
+
+	f, err := os.Open(archivePath)
+	assert.NoError(t, err)
+	defer func() { _ = f.Close() }()
+
+	var files []string
+	r := tar.NewReader(f)
+	for {
+		hdr, err := r.Next()
+		if err == io.EOF {
+			break
+		}
+		assert.NoError(t, err)
+		if hdr.Typeflag == tar.TypeReg {
+			files = append(files, hdr.Name)
+		}
+	}
+	sort.Strings(files)
+	return files
+}
+
+func TestVerifyCommit(t *testing.T) {
+	t.Parallel()
+	s := sandbox.New(t)
+	g := s.Git()
+	root := s.RootEntry()
+
+	if diff := cmp.Diff(g.VerifyCommit("HEAD"), git.SignatureStatus{}); diff != "" {
+		t.Fatalf("unexpected status of unsigned commit (got-, want+):\n%s", diff)
+	}
+
+	sshKeygen, err := exec.LookPath("ssh-keygen")
+	if err != nil {
+		t.Skip("ssh-keygen not found, skipping signed commits")
+	}
+	keyPath := filepath.Join(t.TempDir(), "signing_key")
+	out, err := exec.Command(sshKeygen, "-q", "-t", "ed25519", "-N", "", "-C", test.Email, "-f", keyPath).CombinedOutput()
+	if err != nil {
+		t.Skipf("unable to create signing key, skipping signed commits: %v: %s", err, out)
+	}
+	pubKey, err := os.ReadFile(keyPath + ".pub")
+	assert.NoError(t, err)
+	allowedSigners := filepath.Join(t.TempDir(), "allowed_signers")
+	assert.NoError(t, os.WriteFile(allowedSigners, []byte(test.Email+" "+string(pubKey)), 0o644))
+
+	g.ConfigSet("gpg.format", "ssh")
+	g.ConfigSet("user.signingkey", keyPath)
+	g.ConfigSet("commit.gpgsign", "true")
+
+	root.CreateFile("signed.txt", "signed")
+	g.CommitAll("signed commit")
+
+	status := g.VerifyCommit("HEAD")
+	assert.IsTrue(t, status.Signed)
+	assert.IsTrue(t, !status.Valid, "signature must not be trusted without allowed signers")
+
+	g.ConfigSet("gpg.ssh.allowedSignersFile", allowedSigners)
+	status = g.VerifyCommit("HEAD")
+	assert.IsTrue(t, status.Signed)
+	assert.IsTrue(t, status.Valid)
+	assert.EqualStrings(t, test.Email, status.Signer)
+	assert.IsTrue(t, strings.HasPrefix(status.Key, "SHA256:"), "unexpected key %q", status.Key)
+
+	assert.IsTrue(t, !g.VerifyCommit("HEAD~1").Signed)
+	_, err = g.Unwrap().VerifyCommit("non-existent")
+	assert.Error(t, err)
+}
+
+func TestBlame(t *testing.T) {
+	t.Parallel()
+	s := sandbox.New(t)
+	g := s.Git()
+	root := s.RootEntry()
+
+	root.CreateFile("file.txt", "line 1\nline 2\n")
+	g.CommitAll("first")
+	first := g.RevParse("HEAD")
+
+	root.CreateFile("file.txt", "line 1\nchanged line 2\n\tline 3\n")
+	g.Add("file.txt")
+	g.CommitWith("second", git.CommitOptions{
+		AuthorName:  "Jane Doe",
+		AuthorEmail: "jane@example.com",
+	})
+	second := g.RevParse("HEAD")
+
+	root.CreateFile("file.txt", "line 1\nchanged line 2\n\tline 3\nuncommitted\n")
+
+	want := []git.BlameLine{
+		{Line: 1, Commit: first, Committed: true, Author: test.Username, Email: test.Email, Content: "line 1"},
+		{Line: 2, Commit: second, Committed: true, Author: "Jane Doe", Email: "jane@example.com", Content: "changed line 2"},
+		{Line: 3, Commit: second, Committed: true, Author: "Jane Doe", Email: "jane@example.com", Content: "\tline 3"},
+	}
+	got := g.Blame("file.txt")
+	if len(got) != 4 {
+		t.Fatalf("Blame() returned %d lines, want 4: %v", len(got), got)
+	}
+	if diff := cmp.Diff(got[:3], want); diff != "" {
+		t.Fatalf("unexpected blame (got-, want+):\n%s", diff)
+	}
+
+	uncommitted := got[3]
+	assert.IsTrue(t, !uncommitted.Committed, "line 4 must not be committed")
+	assert.EqualStrings(t, strings.Repeat("0", 40), uncommitted.Commit)
+	assert.EqualStrings(t, "uncommitted", uncommitted.Content)
+	assert.EqualInts(t, 4, uncommitted.Line)
+
+	_, err := g.Unwrap().Blame("non-existent.txt")
+	assert.Error(t, err)
+}
+
+func TestLsFiles(t *testing.T) {
+	t.Parallel()
+	s := sandbox.New(t)
+	g := s.Git()
+	root := s.RootEntry()
+
+	root.CreateFile("main.tf", "")
+	root.CreateFile("stacks/a/main.tf", "")
+	root.CreateFile("stacks/a/_gen.tf", "")
+	root.CreateFile("stacks/b/deep/dir/file name.txt", "")
+	g.CommitAll("add files")
+	root.CreateFile("stacks/a/untracked.tf", "")
+
+	assertEqualStringList(t, g.LsFiles(), []string{
+		".gitignore",
+		"README.md",
+		"main.tf",
+		"stacks/a/_gen.tf",
+		"stacks/a/main.tf",
+		"stacks/b/deep/dir/file name.txt",
+	})
+	assertEqualStringList(t, g.LsFiles("stacks/a"), []string{
+		"stacks/a/_gen.tf",
+		"stacks/a/main.tf",
+	})
+	assertEqualStringList(t, g.LsFiles("*.tf", ":(exclude)stacks/a/_gen.tf"), []string{
+		"main.tf",
+		"stacks/a/main.tf",
+	})
+	assertEqualStringList(t, g.LsFiles("non-existent"), []string{})
+
+	// paths are relative to the repository root even from subdirectories.
+	subg := g.Unwrap().With().WorkingDir(filepath.Join(s.RootDir(), "stacks")).Wrapper()
+	files, err := subg.LsFiles("b")
+	assert.NoError(t, err)
+	assertEqualStringList(t, files, []string{"stacks/b/deep/dir/file name.txt"})
+}
+
+func TestStatus(t *testing.T) {
+	t.Parallel()
+	s := sandbox.New(t)
+	g := s.Git()
+	root := s.RootEntry()
+
+	root.CreateFile("modified.txt", "original")
+	root.CreateFile("staged.txt", "original")
+	root.CreateFile("renamed.txt", "renamed content")
+	root.CreateFile("deleted.txt", "deleted")
+	g.CommitAll("add files")
+	assert.IsTrue(t, g.IsClean())
+
+	root.CreateFile("modified.txt", "changed")
+	root.CreateFile("staged.txt", "changed")
+	g.Add("staged.txt")
+	root.CreateFile("dir/untracked file.txt", "untracked")
+	root.RemoveFile("deleted.txt")
+	_, err := g.Unwrap().Exec("mv", "renamed.txt", "new name.txt")
+	assert.NoError(t, err)
+
+	assert.IsTrue(t, !g.IsClean())
+	want := []git.FileStatus{
+		{Path: "deleted.txt", Staged: '.', Unstaged: 'D'},
+		{Path: "modified.txt", Staged: '.', Unstaged: 'M'},
+		{Path: "new name.txt", OrigPath: "renamed.txt", Staged: 'R', Unstaged: '.'},
+		{Path: "staged.txt", Staged: 'M', Unstaged: '.'},
+		{Path: "dir/untracked file.txt", Staged: '?', Unstaged: '?'},
+	}
+	if diff := cmp.Diff(g.Status(), want); diff != "" {
+		t.Fatalf("unexpected status (got-, want+):\n%s", diff)
+	}
+	assert.IsTrue(t, want[4].IsUntracked())
+	assert.IsTrue(t, !want[3].IsUntracked())
+}
+
 func TestListDirtyFiles(t *testing.T) {
 	t.Parallel()
 	const (
@@ -555,6 +2343,43 @@ func addDefaultRemoteRev(t *testing.T, git *git.Git) (string, string) {
 	return remote, revision
 }
 
+// cloneRepo clones the repository at url into a new temporary directory
+// configured with the test identity.
+func cloneRepo(t *testing.T, url string) *sandbox.Git {
+	t.Helper()
+
+	dir := test.TempDir(t)
+	g := test.NewGitWrapper(t, dir, []string{})
+	assert.NoError(t, g.Clone(url, dir))
+	_, err := g.Exec("config", "user.name", test.Username)
+	assert.NoError(t, err)
+	_, err = g.Exec("config", "user.email", test.Email)
+	assert.NoError(t, err)
+	return sandbox.NewGit(t, dir)
+}
+
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+func assertNoFile(t *testing.T, path string) {
+	t.Helper()
+
+	_, err := os.Stat(path)
+	if !os.IsNotExist(err) {
+		t.Fatalf("expected %s to not exist: stat error: %v", path, err)
+	}
+}
+
+func assertEqualStringList(t *testing.T, got []string, want []string) {
+	t.Helper()
+
+	if diff := cmp.Diff(got, want, cmpopts.EquateEmpty()); diff != "" {
+		t.Fatalf("got %v != want %v. Details (got-, want+):\n%s", got, want, diff)
+	}
+}
+
 func assertEqualRemotes(t *testing.T, got []git.Remote, want []git.Remote) {
 	t.Helper()
 
@@ -187,6 +187,12 @@ func vendor(
 	info *modinfo,
 	events ProgressEventStream,
 ) Report {
+	if modsrc.Registry || modsrc.Local || modsrc.Archive {
+		report.addIgnored(modsrc.Raw, errors.E(ErrUnsupportedModSrc,
+			"only git module sources can be vendored"))
+		return report
+	}
+
 	moddir, err := downloadVendor(rootdir, vendorDir, modsrc, events)
 	if err != nil {
 		if errors.IsKind(err, ErrAlreadyVendored) {
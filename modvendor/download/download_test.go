@@ -150,6 +150,58 @@ func TestDownloadVendor(t *testing.T) {
 				},
 			},
 		},
+		{
+			name: "module with ignored registry deps",
+			layout: []string{
+				"g:module-test",
+			},
+			source: "git::{{.}}/module-test?ref=main",
+			configs: []hclconfig{
+				{
+					repo: "module-test",
+					path: "module-test/main.tf",
+					data: Module(
+						Labels("test"),
+						Str("source", "hashicorp/consul/aws"),
+					),
+				},
+			},
+			wantVendored: []string{
+				"git::{{.}}/module-test?ref=main",
+			},
+			wantIgnored: []wantIgnoredVendor{
+				{
+					RawSource: "hashicorp/consul/aws",
+					Error:     errors.E(download.ErrUnsupportedModSrc),
+				},
+			},
+		},
+		{
+			name: "module with ignored archive deps",
+			layout: []string{
+				"g:module-test",
+			},
+			source: "git::{{.}}/module-test?ref=main",
+			configs: []hclconfig{
+				{
+					repo: "module-test",
+					path: "module-test/main.tf",
+					data: Module(
+						Labels("test"),
+						Str("source", "https://artifacts.acme.com/modules/vpc.zip"),
+					),
+				},
+			},
+			wantVendored: []string{
+				"git::{{.}}/module-test?ref=main",
+			},
+			wantIgnored: []wantIgnoredVendor{
+				{
+					RawSource: "https://artifacts.acme.com/modules/vpc.zip",
+					Error:     errors.E(download.ErrUnsupportedModSrc),
+				},
+			},
+		},
 		{
 			name:   "module not found",
 			source: "git::{{.}}/module-that-does-not-exists?ref=main",
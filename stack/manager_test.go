@@ -498,7 +498,7 @@ func singleMergeCommitRepo(t *testing.T) repository {
 
 	addMergeCommit(t, repo.Dir, "testbranch")
 
-	assert.NoError(t, g.DeleteBranch("testbranch"), "delete testbranch")
+	assert.NoError(t, g.DeleteBranch("testbranch", true), "delete testbranch")
 	return repo
 }
 
@@ -519,7 +519,7 @@ func singleMergeCommitRepoNoStack(t *testing.T) repository {
 
 	addMergeCommit(t, repo.Dir, "testbranch")
 
-	assert.NoError(t, g.DeleteBranch("testbranch"), "delete testbranch")
+	assert.NoError(t, g.DeleteBranch("testbranch", true), "delete testbranch")
 
 	return repo
 }
@@ -543,7 +543,7 @@ func multipleStacksOneChangedRepo(t *testing.T) repository {
 	assert.NoError(t, g.Commit("other stack message"), "commit failed")
 
 	addMergeCommit(t, repo.Dir, "testbranch")
-	assert.NoError(t, g.DeleteBranch("testbranch"), "delete temp branch")
+	assert.NoError(t, g.DeleteBranch("testbranch", true), "delete temp branch")
 
 	// not merged changes
 	assert.NoError(t, g.Checkout("testbranch2", true), "create branch testbranch2 failed")
@@ -648,7 +648,7 @@ module "something" {
 	assert.NoError(t, g.Commit("add main.tf"), "commit main.tf")
 
 	addMergeCommit(t, repo.Dir, "testbranch")
-	assert.NoError(t, g.DeleteBranch("testbranch"), "delete temp branch")
+	assert.NoError(t, g.DeleteBranch("testbranch", true), "delete temp branch")
 
 	mainFile = test.WriteFile(t, module, "main.tf", "")
 	assert.NoError(t, g.Add(mainFile))
@@ -793,7 +793,7 @@ func singleTerragruntStackWithNoChangesRepo(t *testing.T) repository {
 	assert.NoError(t, g.Commit("files"), "commit files")
 
 	addMergeCommit(t, repo.Dir, "testbranch")
-	assert.NoError(t, g.DeleteBranch("testbranch"), "delete testbranch")
+	assert.NoError(t, g.DeleteBranch("testbranch", true), "delete testbranch")
 
 	// now we branch again and modify the module
 	assert.NoError(t, g.Checkout("testbranch2", true), "create branch testbranch2 failed")
@@ -838,7 +838,7 @@ func singleTerragruntStackWithSingleTerraformModuleChangedRepo(t *testing.T, ena
 	assert.NoError(t, g.Commit("files"), "commit files")
 
 	addMergeCommit(t, repo.Dir, "testbranch")
-	assert.NoError(t, g.DeleteBranch("testbranch"), "delete testbranch")
+	assert.NoError(t, g.DeleteBranch("testbranch", true), "delete testbranch")
 
 	// now we branch again and modify the module
 	assert.NoError(t, g.Checkout("testbranch2", true), "create branch testbranch2 failed")
@@ -897,7 +897,7 @@ func singleTerragruntStackWithDependentOnFileChangedRepo(t *testing.T, enabledOp
 	assert.NoError(t, g.Commit("files"), "commit files")
 
 	addMergeCommit(t, repo.Dir, "testbranch")
-	assert.NoError(t, g.DeleteBranch("testbranch"), "delete testbranch")
+	assert.NoError(t, g.DeleteBranch("testbranch", true), "delete testbranch")
 
 	// now we branch again and modify the hello.txt
 	assert.NoError(t, g.Checkout("testbranch2", true), "create branch testbranch2 failed")
@@ -956,7 +956,7 @@ func singleTerragruntStackWithDependentOnFileOutsideRepo(t *testing.T, enabledOp
 	assert.NoError(t, g.Commit("files"), "commit files")
 
 	addMergeCommit(t, repo.Dir, "testbranch")
-	assert.NoError(t, g.DeleteBranch("testbranch"), "delete testbranch")
+	assert.NoError(t, g.DeleteBranch("testbranch", true), "delete testbranch")
 
 	// now we branch again and modify the hello.txt
 	assert.NoError(t, g.Checkout("testbranch2", true), "create branch testbranch2 failed")
@@ -1006,7 +1006,7 @@ func singleTerragruntStackWithSingleTerraformModuleChangedFromAbsPathSourceRepo(
 	assert.NoError(t, g.Commit("files"), "commit files")
 
 	addMergeCommit(t, repo.Dir, "testbranch")
-	assert.NoError(t, g.DeleteBranch("testbranch"), "delete testbranch")
+	assert.NoError(t, g.DeleteBranch("testbranch", true), "delete testbranch")
 
 	// now we branch again and modify the module
 	assert.NoError(t, g.Checkout("testbranch2", true), "create branch testbranch2 failed")
@@ -1057,7 +1057,7 @@ func terragruntStackChangedDueToDependencyChangedRepo(t *testing.T) repository {
 	assert.NoError(t, g.Commit("files"), "commit files")
 
 	addMergeCommit(t, repo.Dir, "testbranch")
-	assert.NoError(t, g.DeleteBranch("testbranch"), "delete testbranch")
+	assert.NoError(t, g.DeleteBranch("testbranch", true), "delete testbranch")
 
 	// now we branch again and modify the dependency module
 	assert.NoError(t, g.Checkout("testbranch2", true), "create branch testbranch2 failed")
@@ -1124,7 +1124,7 @@ func terragruntStackChangedDueToDepOfDepStacksChangedRepo(t *testing.T) reposito
 	assert.NoError(t, g.Commit("files"), "commit files")
 
 	addMergeCommit(t, repo.Dir, "testbranch")
-	assert.NoError(t, g.DeleteBranch("testbranch"), "delete testbranch")
+	assert.NoError(t, g.DeleteBranch("testbranch", true), "delete testbranch")
 
 	// now we branch again and modify the dep-dep-tg-stack module
 	assert.NoError(t, g.Checkout("testbranch2", true), "create branch testbranch2 failed")
@@ -1191,7 +1191,7 @@ func terragruntStackChangedDueToDepOfDepNonStacksChangedRepo(t *testing.T) repos
 	assert.NoError(t, g.Commit("files"), "commit files")
 
 	addMergeCommit(t, repo.Dir, "testbranch")
-	assert.NoError(t, g.DeleteBranch("testbranch"), "delete testbranch")
+	assert.NoError(t, g.DeleteBranch("testbranch", true), "delete testbranch")
 
 	// now we branch again and modify the dep-dep-tg-stack module
 	assert.NoError(t, g.Checkout("testbranch2", true), "create branch testbranch2 failed")
@@ -1261,7 +1261,7 @@ func terragruntStackChangedDueToDepOfDepModuleSourceChangedRepo(t *testing.T) re
 	assert.NoError(t, g.Commit("files"), "commit files")
 
 	addMergeCommit(t, repo.Dir, "testbranch")
-	assert.NoError(t, g.DeleteBranch("testbranch"), "delete testbranch")
+	assert.NoError(t, g.DeleteBranch("testbranch", true), "delete testbranch")
 
 	// now we branch again and modify the dep-dep-tg-stack module
 	assert.NoError(t, g.Checkout("testbranch2", true), "create branch testbranch2 failed")
@@ -1315,7 +1315,7 @@ func terragruntStackChangedDueToReferencedFileChangedRepo(t *testing.T) reposito
 	assert.NoError(t, g.Commit("files"), "commit files")
 
 	addMergeCommit(t, repo.Dir, "testbranch")
-	assert.NoError(t, g.DeleteBranch("testbranch"), "delete testbranch")
+	assert.NoError(t, g.DeleteBranch("testbranch", true), "delete testbranch")
 
 	// now we branch again and modify the common.tfvars file
 	assert.NoError(t, g.Checkout("testbranch2", true), "create branch testbranch2 failed")
@@ -320,6 +320,10 @@ func VendorFunc(basedir, vendordir project.Path, stream chan<- event.VendorReque
 			if err != nil {
 				return cty.NilVal, errors.E(err, "tm_vendor: invalid module source")
 			}
+			if modsrc.Registry || modsrc.Local || modsrc.Archive {
+				return cty.NilVal, errors.E(tf.ErrUnsupportedModSrc,
+					"tm_vendor: module source %q cannot be vendored", source)
+			}
 			targetPath := modvendor.TargetDir(vendordir, modsrc)
 			result, err := filepath.Rel(basedir.String(), targetPath.String())
 			if err != nil {
@@ -98,6 +98,27 @@ func TestTmVendor(t *testing.T) {
 			expr:      `tm_vendor("not a valid module src")`,
 			wantErr:   true,
 		},
+		{
+			name:      "fails on registry module src",
+			vendorDir: "/modules",
+			targetDir: "/dir",
+			expr:      `tm_vendor("hashicorp/consul/aws")`,
+			wantErr:   true,
+		},
+		{
+			name:      "fails on local module src",
+			vendorDir: "/modules",
+			targetDir: "/dir",
+			expr:      `tm_vendor("./modules/vpc")`,
+			wantErr:   true,
+		},
+		{
+			name:      "fails on archive module src",
+			vendorDir: "/modules",
+			targetDir: "/dir",
+			expr:      `tm_vendor("https://artifacts.acme.com/modules/vpc.zip")`,
+			wantErr:   true,
+		},
 		{
 			name:      "fails on parameter missing",
 			vendorDir: "/modules",
@@ -5,6 +5,8 @@ package sandbox
 
 import (
 	"fmt"
+	"os"
+	"path/filepath"
 	"testing"
 	"time"
 
@@ -31,6 +33,14 @@ type Git struct {
 	bareRepo string
 }
 
+var (
+	mergeFFOnly = git.MergeOptions{FFOnly: true}
+	mergeNoFF   = git.MergeOptions{NoFF: true}
+	resetHard   = git.ResetHard
+	fetchPrune  = git.FetchOptions{Prune: true}
+	graphAll    = git.GraphOptions{}
+)
+
 // NewGit creates a new git wrapper using sandbox defaults.
 func NewGit(t testing.TB, repodir string) *Git {
 	t.Helper()
@@ -124,6 +134,39 @@ func (git Git) InitLocalRepo() {
 	}
 }
 
+// InitWithBranch initializes the local repository, without any remote, with
+// the initial branch named name.
+func (git Git) InitWithBranch(name string) {
+	git.t.Helper()
+
+	if err := git.g.Init(git.cfg.repoDir, name, false); err != nil {
+		git.t.Fatalf("Git.Init(%v, %v, false) = %v", git.cfg.repoDir, name, err)
+	}
+}
+
+// Root returns the absolute path of the repository root directory.
+func (git Git) Root() string {
+	git.t.Helper()
+
+	root, err := git.g.Root()
+	if err != nil {
+		git.t.Fatalf("Git.Root() = %v", err)
+	}
+	return root
+}
+
+// IsInsideWorkTree tells if the git working dir is inside the work tree of
+// a repository.
+func (git Git) IsInsideWorkTree() bool {
+	git.t.Helper()
+
+	inside, err := git.g.IsInsideWorkTree()
+	if err != nil {
+		git.t.Fatalf("Git.IsInsideWorkTree() = %v", err)
+	}
+	return inside
+}
+
 // RevParse parses the reference name and returns the reference hash.
 func (git Git) RevParse(ref string) string {
 	git.t.Helper()
@@ -136,12 +179,161 @@ func (git Git) RevParse(ref string) string {
 	return val
 }
 
+// CurrentCommit returns the full commit id of HEAD.
+func (git Git) CurrentCommit() string {
+	git.t.Helper()
+
+	commit, err := git.g.CurrentCommit()
+	if err != nil {
+		git.t.Fatalf("Git.CurrentCommit() = %v", err)
+	}
+	return commit
+}
+
+// ShortHash returns the abbreviated commit id of rev.
+func (git Git) ShortHash(rev string) string {
+	git.t.Helper()
+
+	hash, err := git.g.ShortHash(rev)
+	if err != nil {
+		git.t.Fatalf("Git.ShortHash(%v) = %v", rev, err)
+	}
+	return hash
+}
+
+// ShowFile returns the content of the file at path as it is at the given
+// revision.
+func (git Git) ShowFile(rev, path string) string {
+	git.t.Helper()
+
+	content, err := git.g.ShowFile(rev, path)
+	if err != nil {
+		git.t.Fatalf("Git.ShowFile(%s, %s) = %v", rev, path, err)
+	}
+	return string(content)
+}
+
+// AddNote attaches the note to the rev commit in the default notes ref.
+// Fails the caller test if an error is found.
+func (git Git) AddNote(rev, note string) {
+	git.t.Helper()
+
+	if err := git.g.AddNote(rev, note); err != nil {
+		git.t.Fatalf("Git.AddNote(%s, %s) = %v", rev, note, err)
+	}
+}
+
+// ReadNote returns the note of the rev commit in the default notes ref.
+// Fails the caller test if an error is found.
+func (git Git) ReadNote(rev string) string {
+	git.t.Helper()
+
+	note, err := git.g.ReadNote(rev)
+	if err != nil {
+		git.t.Fatalf("Git.ReadNote(%s) = %v", rev, err)
+	}
+	return note
+}
+
+// Archive writes a tar archive of the tree of rev, restricted to the paths if
+// given, to a temporary file and returns its path.
+// Fails the caller test if an error is found.
+func (git Git) Archive(rev, prefix string, paths ...string) string {
+	git.t.Helper()
+
+	archivePath := filepath.Join(git.t.TempDir(), "archive.tar")
+	f, err := os.Create(archivePath)
+	assert.NoError(git.t, err, "creating archive file")
+	defer func() {
+		assert.NoError(git.t, f.Close(), "closing archive file")
+	}()
+
+	if err := git.g.Archive(rev, prefix, f, paths...); err != nil {
+		git.t.Fatalf("Git.Archive(%s, %s, %v) = %v", rev, prefix, paths, err)
+	}
+	return archivePath
+}
+
+// ConfigGet returns the value of the config key.
+func (git Git) ConfigGet(key string) string {
+	git.t.Helper()
+
+	val, err := git.g.ConfigGet(key)
+	if err != nil {
+		git.t.Fatalf("Git.ConfigGet(%s) = %v", key, err)
+	}
+	return val
+}
+
+// ConfigSet sets the config key to value in the repository configuration.
+func (git Git) ConfigSet(key, value string) {
+	git.t.Helper()
+
+	if err := git.g.ConfigSet(key, value); err != nil {
+		git.t.Fatalf("Git.ConfigSet(%s, %s) = %v", key, value, err)
+	}
+}
+
 // RemoteAdd adds a new remote on the repo
 func (git Git) RemoteAdd(name, url string) {
 	err := git.g.RemoteAdd(name, url)
 	assert.NoError(git.t, err, "Git.RemoteAdd(%v, %v)", name, url)
 }
 
+// DefaultBranch returns the default branch of remote.
+// Fails the caller test if an error is found.
+func (git Git) DefaultBranch(remote string) string {
+	git.t.Helper()
+
+	branch, err := git.g.DefaultBranch(remote)
+	if err != nil {
+		git.t.Fatalf("Git.DefaultBranch(%s) = %v", remote, err)
+	}
+	return branch
+}
+
+// RemoteRemove removes the named remote.
+// Fails the caller test if an error is found.
+func (git Git) RemoteRemove(name string) {
+	git.t.Helper()
+
+	if err := git.g.RemoteRemove(name); err != nil {
+		git.t.Fatalf("Git.RemoteRemove(%s) = %v", name, err)
+	}
+}
+
+// RemoteRename renames the oldName remote to newName.
+// Fails the caller test if an error is found.
+func (git Git) RemoteRename(oldName, newName string) {
+	git.t.Helper()
+
+	if err := git.g.RemoteRename(oldName, newName); err != nil {
+		git.t.Fatalf("Git.RemoteRename(%s, %s) = %v", oldName, newName, err)
+	}
+}
+
+// RemoteURL returns the URL of the named remote as configured.
+func (git Git) RemoteURL(name string) string {
+	git.t.Helper()
+
+	url, err := git.g.RemoteURL(name)
+	if err != nil {
+		git.t.Fatalf("Git.RemoteURL(%s) = %v", name, err)
+	}
+	return url
+}
+
+// ListRemotes returns all the configured remotes.
+func (git Git) ListRemotes() []git.Remote {
+	git.t.Helper()
+
+	remotes, err := git.g.ListRemotes()
+	if err != nil {
+		git.t.Fatalf("Git.ListRemotes() = %v", err)
+	}
+	return remotes
+}
+
 // Add will add files to the commit list
 func (git Git) Add(files ...string) {
 	git.t.Helper()
@@ -151,6 +343,17 @@ func (git Git) Add(files ...string) {
 	}
 }
 
+// AddPathspec stages the files matched by the git pathspecs, which can use
+// magic signatures like :(exclude)path.
+// Fails the caller test if an error is found.
+func (git Git) AddPathspec(pathspecs ...string) {
+	git.t.Helper()
+
+	if err := git.g.Add(pathspecs...); err != nil {
+		git.t.Fatalf("Git.AddPathspec(%v) = %v", pathspecs, err)
+	}
+}
+
 // AddSubmodule adds name as a submodule for the provided url.
 func (git Git) AddSubmodule(name string, url string) {
 	git.t.Helper()
@@ -170,12 +373,106 @@ func (git *Git) CurrentBranch() string {
 	return branch
 }
 
-// DeleteBranch deletes the ref branch.
-func (git Git) DeleteBranch(ref string) {
+// DeleteBranch deletes the local branch, even if unmerged when force is
+// true.
+func (git Git) DeleteBranch(name string, force bool) {
+	git.t.Helper()
+
+	if err := git.g.DeleteBranch(name, force); err != nil {
+		git.t.Fatalf("Git.DeleteBranch(%q, %t) = %v", name, force, err)
+	}
+}
+
+// TryDeleteBranch deletes the local branch, returning any error found.
+func (git Git) TryDeleteBranch(name string, force bool) error {
+	return git.g.DeleteBranch(name, force)
+}
+
+// SetUpstream configures the local branch to track remoteBranch of remote.
+func (git Git) SetUpstream(branch, remote, remoteBranch string) {
 	git.t.Helper()
 
-	if err := git.g.DeleteBranch(ref); err != nil {
-		git.t.Fatalf("Git.DeleteBranch(%q) = %v", ref, err)
+	if err := git.g.SetUpstream(branch, remote, remoteBranch); err != nil {
+		git.t.Fatalf("Git.SetUpstream(%q, %q, %q) = %v", branch, remote, remoteBranch, err)
+	}
+}
+
+// DeleteRemoteBranch deletes the branch from remote.
+func (git Git) DeleteRemoteBranch(remote, name string) {
+	git.t.Helper()
+
+	if err := git.g.DeleteRemoteBranch(remote, name); err != nil {
+		git.t.Fatalf("Git.DeleteRemoteBranch(%q, %q) = %v", remote, name, err)
+	}
+}
+
+// Branches returns the branch names selected by filter.
+func (git Git) Branches(filter git.BranchFilter) []string {
+	git.t.Helper()
+
+	branches, err := git.g.Branches(filter)
+	if err != nil {
+		git.t.Fatalf("Git.Branches(%d) = %v", filter, err)
+	}
+	return branches
+}
+
+// BranchExists tells if there's a local or remote branch with the given name.
+func (git Git) BranchExists(name string) bool {
+	return git.g.BranchExists(name)
+}
+
+// Tag creates a tag pointing to the current HEAD. If message is non-empty an
+// annotated tag is created, otherwise a lightweight tag is created.
+func (git Git) Tag(name, message string) {
+	git.t.Helper()
+
+	if err := git.g.Tag(name, message); err != nil {
+		git.t.Fatalf("Git.Tag(%q, %q) = %v", name, message, err)
+	}
+}
+
+// Tags returns the names of all tags sorted by version.
+func (git Git) Tags() []string {
+	git.t.Helper()
+
+	tags, err := git.g.ListTags()
+	if err != nil {
+		git.t.Fatalf("Git.ListTags() = %v", err)
+	}
+	return tags
+}
+
+// TagsWithCommits returns the names of all tags mapped to the commit they
+// point to, dereferencing annotated tags.
+func (git Git) TagsWithCommits() map[string]string {
+	git.t.Helper()
+
+	tags, err := git.g.TagsWithCommits()
+	if err != nil {
+		git.t.Fatalf("Git.TagsWithCommits() = %v", err)
+	}
+	return tags
+}
+
+// ForEachRef returns the references matching the pattern.
+// Fails the caller test if an error is found.
+func (git Git) ForEachRef(pattern string) []git.Ref {
+	git.t.Helper()
+
+	refs, err := git.g.ForEachRef(pattern)
+	if err != nil {
+		git.t.Fatalf("Git.ForEachRef(%s) = %v", pattern, err)
+	}
+	return refs
+}
+
+// DeleteTag deletes the tag.
+func (git Git) DeleteTag(name string) {
+	git.t.Helper()
+
+	if err := git.g.DeleteTag(name); err != nil {
+		git.t.Fatalf("Git.DeleteTag(%q) = %v", name, err)
 	}
 }
 
@@ -188,6 +485,28 @@ func (git Git) Commit(msg string, args ...string) {
 	}
 }
 
+// CommitWith commits the current staged changes with the author and date
+// overridden by opts.
+// Fails the caller test if an error is found.
+func (git Git) CommitWith(msg string, opts git.CommitOptions) {
+	git.t.Helper()
+
+	if err := git.g.CommitWithOptions(msg, opts); err != nil {
+		git.t.Fatalf("Git.CommitWith(%q, %v) = %v", msg, opts, err)
+	}
+}
+
+// Amend rewrites the last commit with the current staged changes, keeping
+// its message if msg is empty.
+// Fails the caller test if an error is found.
+func (git Git) Amend(msg string, opts git.CommitOptions) {
+	git.t.Helper()
+
+	if err := git.g.Amend(msg, opts); err != nil {
+		git.t.Fatalf("Git.Amend(%q, %v) = %v", msg, opts, err)
+	}
+}
+
 // Clone will clone a repository into the given dir.
 func (git Git) Clone(repoURL, dir string) {
 	git.t.Helper()
@@ -197,6 +516,16 @@ func (git Git) Clone(repoURL, dir string) {
 	}
 }
 
+// CloneBranch will clone a repository into the given dir and checkout the
+// given branch.
+func (git Git) CloneBranch(repoURL, branch, dir string) {
+	git.t.Helper()
+
+	if err := git.g.CloneBranch(repoURL, branch, dir); err != nil {
+		git.t.Fatalf("Git.CloneBranch(%q, %q, %q) = %v", repoURL, branch, dir, err)
+	}
+}
+
 // Push pushes changes from branch onto default remote and same remote branch name.
 func (git Git) Push(branch string) {
 	git.t.Helper()
@@ -207,12 +536,75 @@ func (git Git) Push(branch string) {
 func (git Git) PushOn(remote, remoteBranch, localBranch string) {
 	git.t.Helper()
 
-	err := git.g.Push(remote, fmt.Sprintf("%s:%s", localBranch, remoteBranch))
+	err := git.TryPushOn(remote, remoteBranch, localBranch)
 	if err != nil {
 		git.t.Fatalf("Git.Push(%v, %v) = %v", remote, localBranch, err)
 	}
 }
 
+// TryPush is like [Git.Push] but returns the error instead of failing the test.
+func (git Git) TryPush(branch string) error {
+	return git.TryPushOn(git.cfg.DefaultRemoteName, branch, branch)
+}
+
+// TryPushOn is like [Git.PushOn] but returns the error instead of failing the
+// test.
+func (git Git) TryPushOn(remote, remoteBranch, localBranch string) error {
+	return git.g.Push(remote, fmt.Sprintf("%s:%s", localBranch, remoteBranch))
+}
+
+// PushTags pushes all the local tags onto the default remote.
+// Fails the caller test if an error is found.
+func (git Git) PushTags() {
+	git.t.Helper()
+
+	if err := git.g.PushTags(git.cfg.DefaultRemoteName); err != nil {
+		git.t.Fatalf("Git.PushTags() = %v", err)
+	}
+}
+
+// PushTag pushes the local tag onto the default remote.
+// Fails the caller test if an error is found.
+func (git Git) PushTag(tag string) {
+	git.t.Helper()
+
+	if err := git.TryPushTag(tag); err != nil {
+		git.t.Fatalf("Git.PushTag(%s) = %v", tag, err)
+	}
+}
+
+// TryPushTag is like [Git.PushTag] but returns the error instead of failing
+// the test.
+func (git Git) TryPushTag(tag string) error {
+	return git.g.PushTag(git.cfg.DefaultRemoteName, tag)
+}
+
+// Fetch fetches the refspecs from the default remote.
+func (git Git) Fetch(refspecs ...string) {
+	git.t.Helper()
+	git.FetchOn(git.cfg.DefaultRemoteName, refspecs...)
+}
+
+// FetchOn fetches the refspecs from the given remote.
+func (git Git) FetchOn(remote string, refspecs ...string) {
+	git.t.Helper()
+
+	if err := git.g.Fetch(remote, refspecs...); err != nil {
+		git.t.Fatalf("Git.Fetch(%v, %v) = %v", remote, refspecs, err)
+	}
+}
+
+// FetchPrune fetches from the default remote and removes the remote-tracking
+// refs of branches deleted from it.
+func (git Git) FetchPrune() {
+	git.t.Helper()
+
+	remote := git.cfg.DefaultRemoteName
+	if err := git.g.FetchWithOptions(remote, fetchPrune); err != nil {
+		git.t.Fatalf("Git.FetchPrune(%v) = %v", remote, err)
+	}
+}
+
 // Pull pulls changes from default remote into branch
 func (git Git) Pull(branch string) {
 	git.t.Helper()
@@ -249,6 +641,12 @@ func (git Git) CheckoutNew(rev string) {
 	git.checkout(rev, true)
 }
 
+// TryCheckout is like [Git.Checkout] but returns the error instead of failing
+// the test.
+func (git Git) TryCheckout(rev string) error {
+	return git.g.Checkout(rev, false)
+}
+
 func (git Git) checkout(rev string, create bool) {
 	git.t.Helper()
 
@@ -257,16 +655,387 @@ func (git Git) checkout(rev string, create bool) {
 	}
 }
 
+// CheckoutOrphan creates and checks out the new orphan branch name, with an
+// empty index. Fails the caller test if an error is found.
+func (git Git) CheckoutOrphan(name string) {
+	git.t.Helper()
+
+	if err := git.g.CheckoutOrphan(name); err != nil {
+		git.t.Fatalf("Git.CheckoutOrphan(%s) = %v", name, err)
+	}
+}
+
+// CheckoutPaths restores the files at paths as they are at rev.
+// Fails the caller test if an error is found.
+func (git Git) CheckoutPaths(rev string, paths ...string) {
+	git.t.Helper()
+
+	if err := git.g.CheckoutPaths(rev, paths...); err != nil {
+		git.t.Fatalf("Git.CheckoutPaths(%s, %v) = %v", rev, paths, err)
+	}
+}
+
 // Merge will merge the current branch with the given branch.
 // Fails the caller test if an error is found.
 func (git Git) Merge(branch string) {
 	git.t.Helper()
 
-	if err := git.g.Merge(branch); err != nil {
+	if err := git.TryMerge(branch); err != nil {
 		git.t.Fatalf("Git.Merge(%s) = %v", branch, err)
 	}
 }
 
+// MergeFF will merge the given branch into the current branch only if it
+// can be fast-forwarded.
+// Fails the caller test if an error is found.
+func (git Git) MergeFF(branch string) {
+	git.t.Helper()
+
+	if err := git.g.MergeWithOptions(branch, mergeFFOnly); err != nil {
+		git.t.Fatalf("Git.MergeWithOptions(%s, FFOnly) = %v", branch, err)
+	}
+}
+
+// MergeNoFF will merge the given branch into the current branch always
+// creating a merge commit.
+// Fails the caller test if an error is found.
+func (git Git) MergeNoFF(branch string) {
+	git.t.Helper()
+
+	if err := git.g.MergeWithOptions(branch, mergeNoFF); err != nil {
+		git.t.Fatalf("Git.MergeWithOptions(%s, NoFF) = %v", branch, err)
+	}
+}
+
+// TryMerge is like [Git.Merge] but returns the error instead of failing the
+// test.
+func (git Git) TryMerge(branch string) error {
+	return git.g.Merge(branch)
+}
+
+// ResetHard moves HEAD to rev discarding all staged and unstaged changes.
+// Fails the caller test if an error is found.
+func (git Git) ResetHard(rev string) {
+	git.t.Helper()
+
+	if err := git.g.Reset(resetHard, rev); err != nil {
+		git.t.Fatalf("Git.ResetHard(%s) = %v", rev, err)
+	}
+}
+
+// Clean removes the untracked files, and also the untracked directories and
+// ignored files if requested with opts.
+// Fails the caller test if an error is found.
+func (git Git) Clean(opts git.CleanOptions) {
+	git.t.Helper()
+
+	if err := git.g.Clean(opts); err != nil {
+		git.t.Fatalf("Git.Clean(%+v) = %v", opts, err)
+	}
+}
+
+// CherryPick applies the changes of the rev commit on the current branch.
+// Fails the caller test if an error is found.
+func (git Git) CherryPick(rev string) {
+	git.t.Helper()
+
+	if err := git.TryCherryPick(rev); err != nil {
+		git.t.Fatalf("Git.CherryPick(%s) = %v", rev, err)
+	}
+}
+
+// TryCherryPick applies the changes of the rev commit on the current branch,
+// returning any error found.
+func (git Git) TryCherryPick(rev string) error {
+	return git.g.CherryPick(rev)
+}
+
+// ApplyPatch applies the unified diff patch to the working tree.
+// Fails the caller test if an error is found.
+func (git Git) ApplyPatch(patch []byte) {
+	git.t.Helper()
+
+	if err := git.TryApplyPatch(patch); err != nil {
+		git.t.Fatalf("Git.ApplyPatch() = %v", err)
+	}
+}
+
+// TryApplyPatch applies the unified diff patch to the working tree,
+// returning any error found.
+func (git Git) TryApplyPatch(patch []byte) error {
+	return git.g.ApplyPatch(patch)
+}
+
+// Revert creates a new commit undoing the changes of the rev commit.
+// Fails the caller test if an error is found.
+func (git Git) Revert(rev string) {
+	git.t.Helper()
+
+	if err := git.TryRevert(rev); err != nil {
+		git.t.Fatalf("Git.Revert(%s) = %v", rev, err)
+	}
+}
+
+// TryRevert creates a new commit undoing the changes of the rev commit,
+// returning any error found.
+func (git Git) TryRevert(rev string) error {
+	return git.g.Revert(rev)
+}
+
+// WorktreeAdd checks out rev in a new worktree at path.
+// Fails the caller test if an error is found.
+func (git Git) WorktreeAdd(path, rev string) {
+	git.t.Helper()
+
+	if err := git.g.WorktreeAdd(path, rev); err != nil {
+		git.t.Fatalf("Git.WorktreeAdd(%s, %s) = %v", path, rev, err)
+	}
+}
+
+// WorktreeRemove removes the worktree at path.
+// Fails the caller test if an error is found.
+func (git Git) WorktreeRemove(path string) {
+	git.t.Helper()
+
+	if err := git.g.WorktreeRemove(path); err != nil {
+		git.t.Fatalf("Git.WorktreeRemove(%s) = %v", path, err)
+	}
+}
+
+// Rebase reapplies the commits of the current branch on top of onto.
+// Fails the caller test if an error is found.
+func (git Git) Rebase(onto string) {
+	git.t.Helper()
+
+	if err := git.TryRebase(onto); err != nil {
+		git.t.Fatalf("Git.Rebase(%s) = %v", onto, err)
+	}
+}
+
+// TryRebase reapplies the commits of the current branch on top of onto,
+// returning any error found.
+func (git Git) TryRebase(onto string) error {
+	return git.g.Rebase(onto)
+}
+
+// Stash saves the uncommitted changes of the working tree.
+// Fails the caller test if an error is found.
+func (git Git) Stash() {
+	git.t.Helper()
+
+	if err := git.g.Stash(); err != nil {
+		git.t.Fatalf("Git.Stash() = %v", err)
+	}
+}
+
+// StashPop applies and drops the most recently stashed changes.
+// Fails the caller test if an error is found.
+func (git Git) StashPop() {
+	git.t.Helper()
+
+	if err := git.TryStashPop(); err != nil {
+		git.t.Fatalf("Git.StashPop() = %v", err)
+	}
+}
+
+// TryStashPop applies and drops the most recently stashed changes,
+// returning any error found.
+func (git Git) TryStashPop() error {
+	return git.g.StashPop()
+}
+
+// Show returns the metadata and the patch of the rev commit.
+// Fails the caller test if an error is found.
+func (git Git) Show(rev string) git.CommitDetail {
+	git.t.Helper()
+
+	detail, err := git.g.Show(rev)
+	if err != nil {
+		git.t.Fatalf("Git.Show(%v) = %v", rev, err)
+	}
+	return detail
+}
+
+// VerifyCommit returns the signature status of the rev commit.
+// Fails the caller test if an error is found.
+func (git Git) VerifyCommit(rev string) git.SignatureStatus {
+	git.t.Helper()
+
+	status, err := git.g.VerifyCommit(rev)
+	if err != nil {
+		git.t.Fatalf("Git.VerifyCommit(%s) = %v", rev, err)
+	}
+	return status
+}
+
+// Blame returns the attribution of each line of the file at path.
+// Fails the caller test if an error is found.
+func (git Git) Blame(path string) []git.BlameLine {
+	git.t.Helper()
+
+	lines, err := git.g.Blame(path)
+	if err != nil {
+		git.t.Fatalf("Git.Blame(%v) = %v", path, err)
+	}
+	return lines
+}
+
+// LsFiles returns the tracked files matched by the pathspec patterns, or all
+// tracked files if no pattern is given.
+// Fails the caller test if an error is found.
+func (git Git) LsFiles(patterns ...string) []string {
+	git.t.Helper()
+
+	files, err := git.g.LsFiles(patterns...)
+	if err != nil {
+		git.t.Fatalf("Git.LsFiles(%v) = %v", patterns, err)
+	}
+	return files
+}
+
+// Status returns the status of the changed files in the working tree.
+func (git Git) Status() []git.FileStatus {
+	git.t.Helper()
+
+	status, err := git.g.Status()
+	if err != nil {
+		git.t.Fatalf("Git.Status() = %v", err)
+	}
+	return status
+}
+
+// Diff returns the differences between the from and to revisions.
+// If nameOnly is set then only the changed file names are returned.
+func (git Git) Diff(from, to string, nameOnly bool) string {
+	git.t.Helper()
+
+	diff, err := git.g.Diff(from, to, nameOnly)
+	if err != nil {
+		git.t.Fatalf("Git.Diff(%s, %s, %t) = %v", from, to, nameOnly, err)
+	}
+	return diff
+}
+
+// DiffNames returns the names of the files changed between the from and to
+// revisions, including both names of renamed files.
+func (git Git) DiffNames(from, to string) []string {
+	git.t.Helper()
+
+	names, err := git.g.DiffNames(from, to)
+	if err != nil {
+		git.t.Fatalf("Git.DiffNames(%s, %s) = %v", from, to, err)
+	}
+	return names
+}
+
+// RevList returns the commit ids selected by opts.
+func (git Git) RevList(opts git.RevListOptions) []string {
+	git.t.Helper()
+
+	commits, err := git.g.RevList(opts)
+	if err != nil {
+		git.t.Fatalf("Git.RevList(%+v) = %v", opts, err)
+	}
+	return commits
+}
+
+// CountAhead returns the number of commits of HEAD not reachable from base.
+func (git Git) CountAhead(base string) int {
+	git.t.Helper()
+
+	count, err := git.g.CountAhead(base)
+	if err != nil {
+		git.t.Fatalf("Git.CountAhead(%s) = %v", base, err)
+	}
+	return count
+}
+
+// CommitCount returns the number of commits reachable from rev.
+func (git Git) CommitCount(rev string) int {
+	git.t.Helper()
+
+	count, err := git.g.CommitCount(rev)
+	if err != nil {
+		git.t.Fatalf("Git.CommitCount(%s) = %v", rev, err)
+	}
+	return count
+}
+
+// DumpGraph logs the commit graph of all the refs of the repository with t.
+// It's meant for debugging tests and it never fails the test.
+func (git Git) DumpGraph(t testing.TB) {
+	t.Helper()
+
+	graph, err := git.g.GraphLog(graphAll)
+	if err != nil {
+		t.Logf("Git.GraphLog() = %v", err)
+		return
+	}
+	t.Logf("commit graph of %s:\n%s", git.cfg.repoDir, graph)
+}
+
+// CountCommits returns the number of commits of HEAD not reachable from rev
+// that change path.
+func (git Git) CountCommits(rev, path string) int {
+	git.t.Helper()
+
+	count, err := git.g.CountCommits(rev, path)
+	if err != nil {
+		git.t.Fatalf("Git.CountCommits(%s, %s) = %v", rev, path, err)
+	}
+	return count
+}
+
+// Log returns the commits of the history selected by opts.
+func (git Git) Log(opts git.LogOptions) []git.Commit {
+	git.t.Helper()
+
+	commits, err := git.g.Log(opts)
+	if err != nil {
+		git.t.Fatalf("Git.Log(%+v) = %v", opts, err)
+	}
+	return commits
+}
+
+// HasStagedChanges tells if the index has changes not yet committed.
+func (git Git) HasStagedChanges() bool {
+	git.t.Helper()
+
+	staged, err := git.g.HasStagedChanges()
+	if err != nil {
+		git.t.Fatalf("Git.HasStagedChanges() = %v", err)
+	}
+	return staged
+}
+
+// HasUnstagedChanges tells if tracked files have changes not yet staged.
+func (git Git) HasUnstagedChanges() bool {
+	git.t.Helper()
+
+	unstaged, err := git.g.HasUnstagedChanges()
+	if err != nil {
+		git.t.Fatalf("Git.HasUnstagedChanges() = %v", err)
+	}
+	return unstaged
+}
+
+// IsClean tells if the working tree has no changes or untracked files.
+func (git Git) IsClean() bool {
+	git.t.Helper()
+	return len(git.Status()) == 0
+}
+
+// MergeBase returns the common commit ancestor of rev1 and rev2.
+func (git Git) MergeBase(rev1, rev2 string) string {
+	git.t.Helper()
+
+	commit, err := git.g.MergeBase(rev1, rev2)
+	if err != nil {
+		git.t.Fatalf("Git.MergeBase(%s, %s) = %v", rev1, rev2, err)
+	}
+	return commit
+}
+
 // SetRemoteURL sets the URL of the remote.
 func (git Git) SetRemoteURL(remote, url string) {
 	git.t.Helper()
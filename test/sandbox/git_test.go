@@ -6,6 +6,7 @@ package sandbox_test
 import (
 	"testing"
 
+	"github.com/madlambda/spells/assert"
 	"github.com/rs/zerolog"
 	"github.com/terramate-io/terramate/test"
 	"github.com/terramate-io/terramate/test/sandbox"
@@ -37,6 +38,20 @@ func TestInitializeArbitraryRemote(t *testing.T) {
 	git.RevParse(remote + "/" + remoteBranch)
 }
 
+func TestTryMethodsReturnErrors(t *testing.T) {
+	t.Parallel()
+	basedir := test.TempDir(t)
+	git := sandbox.NewGit(t, basedir)
+	git.Init()
+
+	assert.Error(t, git.TryCheckout("non-existent"))
+	assert.Error(t, git.TryMerge("non-existent"))
+	assert.Error(t, git.TryPushOn("non-existent", "main", "main"))
+
+	assert.NoError(t, git.TryPush("main"))
+	assert.NoError(t, git.TryCheckout("main"))
+}
+
 func init() {
 	zerolog.SetGlobalLevel(zerolog.Disabled)
 }
@@ -65,6 +65,15 @@ type StackEntry struct {
 	DirEntry
 }
 
+// StackOpts are the options used by [S.CreateStack] to configure the
+// stack block of the created stack.
+type StackOpts struct {
+	Name        string
+	Description string
+	After       []string
+	Before      []string
+}
+
 // FileEntry represents a file and can be used to manipulate the file contents.
 // It is optimized for reading/writing all contents, not stream programming
 // (io.Reader/io.Writer).
@@ -219,6 +228,42 @@ func (s S) AssertTree(layout []string, opts ...AssertTreeOption) {
 	assertTree(s.t, s.Config(), layout, o)
 }
 
+// AssertGenerated asserts that the file at relpath, relative to the sandbox
+// root dir, exists and has the wantContent. Trailing newlines are ignored
+// when comparing the contents.
+func (s S) AssertGenerated(relpath, wantContent string) {
+	t := s.t
+	t.Helper()
+
+	abspath := filepath.Join(s.RootDir(), filepath.FromSlash(relpath))
+	got, err := os.ReadFile(abspath)
+	if err != nil {
+		t.Fatalf("AssertGenerated(%q): reading file: %v", relpath, err)
+	}
+
+	gotContent := strings.TrimRight(string(got), "\n")
+	wantContent = strings.TrimRight(wantContent, "\n")
+	if diff := cmp.Diff(gotContent, wantContent); diff != "" {
+		t.Fatalf("AssertGenerated(%q): content mismatch (-got +want):\n%s", relpath, diff)
+	}
+}
+
+// AssertNoFile asserts that there is no file at relpath, relative to the
+// sandbox root dir.
+func (s S) AssertNoFile(relpath string) {
+	t := s.t
+	t.Helper()
+
+	abspath := filepath.Join(s.RootDir(), filepath.FromSlash(relpath))
+	_, err := os.Lstat(abspath)
+	if err == nil {
+		t.Fatalf("AssertNoFile(%q): file exists", relpath)
+	}
+	if !os.IsNotExist(err) {
+		t.Fatalf("AssertNoFile(%q): checking file: %v", relpath, err)
+	}
+}
+
 // AssertTreeOption is the common option type for AssertTree.
 type AssertTreeOption func(o *assertTreeOptions)
 
@@ -353,22 +398,39 @@ func (s S) CreateModule(relpath string) DirEntry {
 
 // CreateStack will create a stack dir with the given relative path and
 // initializes the stack, returning a stack entry that can be used
-// to create files inside the stack dir.
+// to create files inside the stack dir. The optional opts configure the
+// name, description and ordering of the stack block, only a single value
+// is accepted.
 //
 // If the path is absolute, it will be considered in relation to the sandbox
 // root dir.
-func (s S) CreateStack(relpath string) StackEntry {
+func (s S) CreateStack(relpath string, opts ...StackOpts) StackEntry {
 	t := s.t
 	t.Helper()
 
+	if len(opts) > 1 {
+		t.Fatalf("CreateStack() accepts a single StackOpts but given %d", len(opts))
+	}
+
 	if filepath.IsAbs(relpath) {
 		relpath = relpath[1:]
 	}
 
+	var opt StackOpts
+	if len(opts) == 1 {
+		opt = opts[0]
+	}
+
 	st := newStackEntry(t, s.RootDir(), relpath)
 	assert.NoError(t, stack.Create(
 		s.Config(),
-		config.Stack{Dir: project.PrjAbsPath(s.RootDir(), st.Path())},
+		config.Stack{
+			Dir:         project.PrjAbsPath(s.RootDir(), st.Path()),
+			Name:        opt.Name,
+			Description: opt.Description,
+			After:       opt.After,
+			Before:      opt.Before,
+		},
 	))
 	return st
 }
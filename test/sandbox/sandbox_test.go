@@ -6,6 +6,7 @@ package sandbox_test
 import (
 	"testing"
 
+	"github.com/madlambda/spells/assert"
 	"github.com/terramate-io/terramate/test/sandbox"
 )
 
@@ -25,3 +26,40 @@ func TestSandboxWithArbitraryRepoConfig(t *testing.T) {
 	git.RevParse(localBranch)
 	git.RevParse(remote + "/" + remoteBranch)
 }
+
+func TestCreateStackWithOpts(t *testing.T) {
+	t.Parallel()
+	s := sandbox.NoGit(t, true)
+	s.CreateStack("stacks/other")
+
+	st := s.CreateStack("stacks/deep/nested/stack", sandbox.StackOpts{
+		Name:        "nested",
+		Description: "nested stack",
+		After:       []string{"/stacks/other"},
+		Before:      []string{"/stacks/after"},
+	})
+	assert.EqualStrings(t, "stacks/deep/nested/stack", st.RelPath())
+
+	loaded := st.Load(s.Config())
+	assert.EqualStrings(t, "nested", loaded.Name)
+	assert.EqualStrings(t, "nested stack", loaded.Description)
+	assert.EqualInts(t, 1, len(loaded.After))
+	assert.EqualStrings(t, "/stacks/other", loaded.After[0])
+	assert.EqualInts(t, 1, len(loaded.Before))
+	assert.EqualStrings(t, "/stacks/after", loaded.Before[0])
+}
+
+func TestAssertGenerated(t *testing.T) {
+	t.Parallel()
+	s := sandbox.NoGit(t, true)
+	s.BuildTree([]string{
+		"s:stack",
+		`f:stack/generate.tm.hcl:generate_file "file.txt" {
+			content = "generated"
+		}`,
+	})
+	s.Generate()
+
+	s.AssertGenerated("stack/file.txt", "generated\n\n")
+	s.AssertNoFile("stack/other.txt")
+}
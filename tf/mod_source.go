@@ -0,0 +1,539 @@
+// Copyright 2024 Terramate GmbH
+// SPDX-License-Identifier: MPL-2.0
+
+package tf
+
+import (
+	"net/url"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/terramate-io/terramate/errors"
+)
+
+// ErrModSrcOutsideRoot indicates that a local module source points outside
+// of the project root.
+const ErrModSrcOutsideRoot errors.Kind = "local module source outside of project root"
+
+// ErrModuleEscapesRoot indicates that the local module source linted by
+// [LintLocalSource] escapes the project root.
+const ErrModuleEscapesRoot errors.Kind = "local module source escapes the project root"
+
+// String returns the canonical Terraform module source for s.
+// The result is built from the parsed fields and not from [Source.Raw], so
+// it is a normalized form of the original source. The zero [Source] gives
+// an empty string.
+func (s Source) String() string {
+	return s.StringWithOptions(ParseOptions{})
+}
+
+// StringWithOptions is like [Source.String] but the sources of the
+// [ParseOptions.GitHubHosts] are also written in the GitHub shorthand form,
+// eg.: github.acme.com/org/repo, so the result must be parsed with the same
+// options. The other options are ignored.
+func (s Source) StringWithOptions(opts ParseOptions) string {
+	var b strings.Builder
+
+	if s == (Source{}) {
+		return ""
+	}
+	if s.Local {
+		return s.Path
+	}
+
+	query := s.QueryValues()
+	switch {
+	case s.Registry:
+		b.WriteString(strings.TrimPrefix(s.Path, DefaultRegistryHost+"/"))
+		if s.Version != "" {
+			query.Set("version", s.Version)
+		}
+	case s.Archive:
+		b.WriteString(s.URL)
+	case s.PathScheme == "git":
+		// scp-like sources are written verbatim.
+		b.WriteString(s.URL)
+	case s.isHostShorthand(opts.githubHosts()):
+		b.WriteString(s.Path)
+	default:
+		b.WriteString("git::")
+		b.WriteString(s.URL)
+	}
+
+	if s.Subdir != "" {
+		b.WriteString("/")
+		b.WriteString(s.Subdir)
+	}
+
+	if s.Ref != "" {
+		query.Set("ref", s.Ref)
+	}
+	if len(query) > 0 {
+		b.WriteString("?")
+		b.WriteString(query.Encode())
+	}
+	return b.String()
+}
+
+// Redacted returns the canonical module source of s, as [Source.String],
+// with the credentials removed so it's safe for logging. The whole userinfo
+// of http(s) URLs is removed, since tokens are commonly used as the username,
+// while for other schemes only the password is removed. The sshkey query
+// parameter, holding a private key, is also removed.
+func (s Source) Redacted() string {
+	if u, err := url.Parse(s.URL); err == nil && u.User != nil && strings.Contains(s.URL, "://") {
+		switch {
+		case u.Scheme == "http" || u.Scheme == "https":
+			u.User = nil
+		default:
+			u.User = url.User(u.User.Username())
+		}
+		s.URL = u.String()
+	}
+
+	query := s.QueryValues()
+	query.Del("sshkey")
+	s.Query = query.Encode()
+	return s.String()
+}
+
+// LintLocalSource checks that the local source src used by the stack at the
+// stackDir host directory resolves to a path inside of rootDir, including
+// rootDir itself, so modules live inside the repository. An error of kind
+// [ErrModuleEscapesRoot], wrapping the [ErrModSrcOutsideRoot] error of
+// [Source.ResolveLocal], is returned otherwise. Remote sources always pass.
+func LintLocalSource(src Source, stackDir, rootDir string) error {
+	if !src.Local {
+		return nil
+	}
+	if _, err := src.ResolveLocal(rootDir, stackDir); err != nil {
+		return errors.E(ErrModuleEscapesRoot, err)
+	}
+	return nil
+}
+
+// SubdirPath returns the cleaned path of the module inside the packageDir
+// directory, where the package of s was fetched. It's packageDir itself if s
+// has no subdir. The result is relative if packageDir is relative.
+// Subdirs escaping the package root are rejected by [Source.Validate], so the
+// result of valid sources is always inside of packageDir.
+func (s Source) SubdirPath(packageDir string) string {
+	subdir := filepath.FromSlash(strings.TrimPrefix(s.Subdir, "/"))
+	return filepath.Clean(filepath.Join(packageDir, subdir))
+}
+
+// packageDirReplacer replaces the path characters that are unsafe or
+// ambiguous in directory names.
+var packageDirReplacer = strings.NewReplacer(
+	"/", "_",
+	"\\", "_",
+	":", "-",
+	"@", "-",
+	"?", "-",
+	"*", "-",
+	"\"", "-",
+	"<", "-",
+	">", "-",
+	"|", "-",
+)
+
+// Slug returns the [Source.Path] of s without the host, eg.: acme/infra for
+// github.com/acme/infra. Registry sources return namespace/name and local
+// sources return the cleaned local path.
+func (s Source) Slug() string {
+	switch {
+	case s.Local:
+		return s.Path
+	case s.Registry:
+		return s.Namespace + "/" + s.Name
+	case s.Host != "":
+		if slug, ok := strings.CutPrefix(s.Path, s.Host+"/"); ok {
+			return slug
+		}
+	}
+	return s.Path
+}
+
+// RepoIdentity returns the host independent identity of the repository of s,
+// which is its [Source.Slug], eg.: acme/infra for both
+// github.com/acme/infra//vpc?ref=v1 and git::https://mirror.acme.com/acme/infra.git
+// It's useful to dedup sources of repositories intentionally mirrored on many
+// hosts, but it's a weaker identity than [Source.SamePackage], as unrelated
+// repositories on different hosts may share it. Local sources have no
+// repository, so their identity is empty.
+func (s Source) RepoIdentity() string {
+	if s.Local {
+		return ""
+	}
+	return s.Slug()
+}
+
+// PackageDir returns a filesystem-safe directory name for the package of s,
+// derived from its [Source.Path], so it's the same for all the subdirs and
+// refs of the package. Eg.: github.com/terramate-io/example//vpc?ref=v1
+// gives github.com_terramate-io_example
+func (s Source) PackageDir() string {
+	return packageDirReplacer.Replace(strings.TrimSuffix(s.Path, ".git"))
+}
+
+// PackageDirWithRef is like [Source.PackageDir] but with the normalized ref
+// appended, so different refs of the same package get different directories.
+// Eg.: github.com_terramate-io_example@v1
+// It's the same as [Source.PackageDir] if s has no ref.
+func (s Source) PackageDirWithRef() string {
+	ref := s.NormalizedRef()
+	if ref == "" {
+		return s.PackageDir()
+	}
+	return s.PackageDir() + "@" + packageDirReplacer.Replace(ref)
+}
+
+// IsRemote tells if fetching s requires network access. Only local
+// filesystem paths are not remote.
+func (s Source) IsRemote() bool {
+	return !s.Local
+}
+
+// Validate checks the invariants of s, so hand built sources are held to
+// the same rules as the ones returned by [ParseSource]. An error of kind
+// [ErrInvalidModSrc] describing the offending field is returned if s is
+// invalid.
+func (s Source) Validate() error {
+	switch {
+	case s.Path == "":
+		return errors.E(ErrInvalidModSrc, "source %q has an empty Path", s.Raw)
+	case s.Subdir != "" && !strings.HasPrefix(s.Subdir, "/"):
+		return errors.E(ErrInvalidModSrc,
+			"source %q has Subdir %q without a leading slash", s.Raw, s.Subdir)
+	case subdirEscapes(s.Subdir):
+		return errors.E(ErrInvalidModSrc,
+			"source %q has Subdir %q escaping the package root", s.Raw, s.Subdir)
+	case strings.IndexFunc(s.Ref, unicode.IsSpace) != -1:
+		return errors.E(ErrInvalidModSrc,
+			"source %q has Ref %q with whitespace", s.Raw, s.Ref)
+	case s.IsRemote() && !s.Registry && s.URL == "":
+		return errors.E(ErrInvalidModSrc,
+			"remote source %q has an empty URL", s.Raw)
+	case s.IsRemote() && !s.Registry && s.Host != "" && !s.hasRepoPath():
+		return errors.E(ErrInvalidModSrc,
+			"source %q has Path %q without a repository path after the host",
+			s.Raw, s.Path)
+	}
+	return nil
+}
+
+// subdirEscapes tells if the ".." segments of subdir go above the package
+// root, eg.: /../etc or /a/../../etc
+func subdirEscapes(subdir string) bool {
+	if !strings.Contains(subdir, "..") {
+		return false
+	}
+	depth := 0
+	for _, segment := range strings.Split(subdir, "/") {
+		switch segment {
+		case "", ".":
+		case "..":
+			depth--
+			if depth < 0 {
+				return true
+			}
+		default:
+			depth++
+		}
+	}
+	return false
+}
+
+// hasRepoPath tells if the [Source.Path] of s has a repository path after
+// the host, ignoring the slashes and the .git suffix.
+func (s Source) hasRepoPath() bool {
+	repo := strings.TrimPrefix(s.Path, s.Host)
+	return strings.Trim(strings.TrimSuffix(repo, ".git"), "/") != ""
+}
+
+// ResolveLocal returns the cleaned absolute host path of the local source s
+// used by the stack at the stackDir host directory. Relative paths are
+// resolved from stackDir. An error of kind [ErrModSrcOutsideRoot] is returned
+// if the resolved path is outside of projectRoot and remote sources fail with
+// [ErrUnsupportedModSrc].
+func (s Source) ResolveLocal(projectRoot, stackDir string) (string, error) {
+	if !s.Local {
+		return "", errors.E(ErrUnsupportedModSrc,
+			"source %q is not a local path", s.Raw)
+	}
+
+	modpath := filepath.FromSlash(s.Path)
+	if !filepath.IsAbs(modpath) {
+		modpath = filepath.Join(stackDir, modpath)
+	}
+	modpath = filepath.Clean(modpath)
+
+	relpath, err := filepath.Rel(projectRoot, modpath)
+	if err != nil || relpath == ".." ||
+		strings.HasPrefix(relpath, ".."+string(filepath.Separator)) {
+		return "", errors.E(ErrModSrcOutsideRoot,
+			"source %q resolves to %q which is outside of %q",
+			s.Raw, modpath, projectRoot)
+	}
+	return modpath, nil
+}
+
+// NormalizedRef returns [Source.Ref] normalized by [NormalizeRef].
+func (s Source) NormalizedRef() string {
+	return NormalizeRef(s.Ref)
+}
+
+// RefType is the kind of ref of a source, as classified by [Source.RefType].
+type RefType int
+
+const (
+	// RefNone is the type of an empty ref.
+	RefNone RefType = iota
+
+	// RefSemver is the type of a semantic version tag. Eg.: v1.2.3 or 1.2.3
+	RefSemver
+
+	// RefSHA is the type of a full commit id, in its SHA-1 (40 hex digits) or
+	// SHA-256 (64 hex digits) form.
+	RefSHA
+
+	// RefBranch is the type of any other ref, which is assumed to be a branch.
+	RefBranch
+)
+
+var (
+	semverRefRegex = regexp.MustCompile(`^v?(0|[1-9][0-9]*)\.(0|[1-9][0-9]*)\.(0|[1-9][0-9]*)(-[0-9A-Za-z.-]+)?(\+[0-9A-Za-z.-]+)?$`)
+	shaRefRegex    = regexp.MustCompile(`^([0-9a-fA-F]{40}|[0-9a-fA-F]{64})$`)
+)
+
+// RefType classifies the normalized ref of s. The classification is
+// heuristic: abbreviated commit ids can't be told apart from branch names, so
+// they are of type [RefBranch], and so are tags not following semver.
+func (s Source) RefType() RefType {
+	ref := s.NormalizedRef()
+	switch {
+	case ref == "":
+		return RefNone
+	case shaRefRegex.MatchString(ref):
+		return RefSHA
+	case semverRefRegex.MatchString(ref):
+		return RefSemver
+	default:
+		return RefBranch
+	}
+}
+
+// SameModule tells if s and other refer to the same module package pinned at
+// the same ref. The refs are compared in their normalized form, so
+// refs/tags/v1 and v1 are considered the same ref. An empty ref is only
+// equal to another empty ref. The host is compared ignoring case, as done by
+// [Source.SamePackage].
+func (s Source) SameModule(other Source) bool {
+	s, other = s.lowerHost(), other.lowerHost()
+	return s.Path == other.Path && s.NormalizedRef() == other.NormalizedRef()
+}
+
+// SamePackage tells if s and other refer to the same module, at the same
+// subdir of the same package, regardless of the ref they are pinned at. Unlike
+// [Source.Equal] the [Source.Ref] and [Source.Raw] fields are ignored, so
+// sources only differing by the version they use are the same package.
+//
+// Hostnames are case-insensitive, so the host is compared ignoring case but
+// the rest of the path is case-sensitive, eg.: GitHub.com/org/repo is the
+// same package as github.com/org/repo but not as github.com/Org/Repo.
+func (s Source) SamePackage(other Source) bool {
+	s, other = s.lowerHost(), other.lowerHost()
+	return s.Host == other.Host && s.Path == other.Path && s.Subdir == other.Subdir
+}
+
+// Equal tells if s and other are the same source after normalization.
+// The [Source.Raw] field is ignored, the refs are compared with
+// [NormalizeRef] and an optional .git suffix on the URL is ignored, so
+// sources that only differ on how they were written are equal.
+// As in [Source.SamePackage], only the host is compared ignoring case.
+func (s Source) Equal(other Source) bool {
+	return s.normalized() == other.normalized()
+}
+
+// DedupSources returns the sources with all [Source.Equal] duplicates removed.
+// The order of the first occurrence of each source is preserved.
+func DedupSources(sources []Source) []Source {
+	seen := map[Source]struct{}{}
+	var deduped []Source
+	for _, src := range sources {
+		key := src.normalized()
+		if _, ok := seen[key]; ok {
+			continue
+		}
+		seen[key] = struct{}{}
+		deduped = append(deduped, src)
+	}
+	return deduped
+}
+
+// GroupSourcesByHost buckets the sources by their [Source.Host], keeping the
+// order of the sources inside each bucket. Local sources have no host, so
+// they are grouped under the empty key. Use [SortedHosts] to iterate over
+// the groups in a deterministic order.
+func GroupSourcesByHost(sources []Source) map[string][]Source {
+	groups := map[string][]Source{}
+	for _, src := range sources {
+		host := src.Host
+		if src.Local {
+			host = ""
+		}
+		groups[host] = append(groups[host], src)
+	}
+	return groups
+}
+
+// SortedHosts returns the hosts of the groups built by [GroupSourcesByHost]
+// sorted lexicographically, so the empty key of local sources comes first.
+func SortedHosts(groups map[string][]Source) []string {
+	hosts := make([]string, 0, len(groups))
+	for host := range groups {
+		hosts = append(hosts, host)
+	}
+	sort.Strings(hosts)
+	return hosts
+}
+
+func (s Source) normalized() Source {
+	s = s.lowerHost()
+	s.Raw = ""
+	s.URL = strings.TrimSuffix(s.URL, ".git")
+	s.Ref = NormalizeRef(s.Ref)
+	return s
+}
+
+// lowerHost returns s with the host lowercased in the Host, Path and URL
+// fields, as it's written verbatim on them.
+func (s Source) lowerHost() Source {
+	host := strings.ToLower(s.Host)
+	if host == s.Host {
+		return s
+	}
+	if rest, ok := strings.CutPrefix(s.Path, s.Host); ok {
+		s.Path = host + rest
+	}
+	s.URL = strings.Replace(s.URL, s.Host, host, 1)
+	s.Host = host
+	return s
+}
+
+// WithRef returns a copy of s pinned at newRef. The [Source.Raw] field, if
+// set, is rewritten with [ReplaceRef], so it keeps the original formatting
+// and it's always consistent with the new [Source.Ref]. As in [ReplaceRef],
+// sources without refs, like local, registry and archive sources, fail with
+// [ErrUnsupportedModSrc] and an empty newRef fails with [ErrInvalidModSrc].
+func (s Source) WithRef(newRef string) (Source, error) {
+	if err := s.checkNewRef(newRef); err != nil {
+		return Source{}, err
+	}
+	if s.Raw != "" {
+		raw, err := ReplaceRef(s.Raw, newRef)
+		if err != nil {
+			return Source{}, err
+		}
+		s.Raw = raw
+	}
+	s.Ref = newRef
+	if err := s.Validate(); err != nil {
+		return Source{}, err
+	}
+	return s, nil
+}
+
+// checkNewRef checks that s can be pinned at newRef.
+func (s Source) checkNewRef(newRef string) error {
+	if s.Local || s.Registry || s.Archive {
+		return errors.E(ErrUnsupportedModSrc,
+			"source %q doesn't support refs", s.Raw)
+	}
+	if newRef == "" {
+		return errors.E(ErrInvalidModSrc, "empty ref for source %q", s.Raw)
+	}
+	return nil
+}
+
+// ReplaceRef replaces the ref of the git module source modsource by newRef,
+// keeping the rest of the string unchanged, or adds the ref parameter if
+// modsource has no ref. Duplicated ref parameters are replaced by a single
+// one. Sources without refs, like local, registry and archive sources, fail
+// with [ErrUnsupportedModSrc].
+func ReplaceRef(modsource, newRef string) (string, error) {
+	src, err := ParseSource(modsource)
+	if err != nil {
+		return "", err
+	}
+	if err := src.checkNewRef(newRef); err != nil {
+		return "", err
+	}
+
+	// The ref is escaped but slashes are kept as they are common in refs
+	// and allowed in queries. Eg.: refs/tags/v1
+	refparam := "ref=" + strings.ReplaceAll(url.QueryEscape(newRef), "%2F", "/")
+
+	addr, query, ok := strings.Cut(modsource, "?")
+	if !ok {
+		return modsource + "?" + refparam, nil
+	}
+
+	// The new ref is set at the position of the first ref parameter.
+	refpos := -1
+	var params []string
+	if query != "" {
+		for _, param := range strings.Split(query, "&") {
+			key, value, _ := strings.Cut(param, "=")
+			if key != "ref" {
+				params = append(params, param)
+				continue
+			}
+			if refpos == -1 {
+				refpos = len(params)
+				params = append(params, refparam)
+			}
+			// A subdir written after the ref must be kept after the new ref.
+			// Eg.: github.com/org/repo?ref=v1//mod
+			if _, subdir, ok := strings.Cut(value, "//"); ok {
+				params[refpos] += "//" + subdir
+			}
+		}
+	}
+	if refpos == -1 {
+		params = append(params, refparam)
+	}
+	return addr + "?" + strings.Join(params, "&"), nil
+}
+
+// NormalizeRef canonicalizes the common forms of a git ref by stripping the
+// refs/tags/ and refs/heads/ prefixes. Eg.: refs/tags/v1.0.0 becomes v1.0.0.
+func NormalizeRef(ref string) string {
+	for _, prefix := range []string{"refs/tags/", "refs/heads/"} {
+		if strings.HasPrefix(ref, prefix) {
+			return strings.TrimPrefix(ref, prefix)
+		}
+	}
+	return ref
+}
+
+// QueryValues returns the parsed [Source.Query].
+func (s Source) QueryValues() url.Values {
+	// Query is always encoded by the parser, so errors are ignored.
+	query, _ := url.ParseQuery(s.Query)
+	return query
+}
+
+// isHostShorthand tells if s can be written using the Github/Bitbucket/Gitlab
+// shorthand notation, which has no scheme prefix. The githubHosts are the
+// hosts with the Github semantics.
+func (s Source) isHostShorthand(githubHosts []string) bool {
+	if !hasHostPrefix(s.Path, githubHosts...) &&
+		!hasHostPrefix(s.Path, "bitbucket.org", "gitlab.com") {
+		return false
+	}
+	return s.URL == "https://"+s.Path+".git"
+}
@@ -0,0 +1,35 @@
+// Copyright 2024 Terramate GmbH
+// SPDX-License-Identifier: MPL-2.0
+
+package tf
+
+import "sync"
+
+// sourceCache maps raw module sources to their parseResult.
+var sourceCache sync.Map
+
+type parseResult struct {
+	src Source
+	err error
+}
+
+// ParseSourceCached is like [ParseSource] but memoizes the results, including
+// the errors, by the modsource string. It's safe for concurrent use.
+func ParseSourceCached(modsource string) (Source, error) {
+	if cached, ok := sourceCache.Load(modsource); ok {
+		res := cached.(parseResult)
+		return res.src, res.err
+	}
+	src, err := ParseSource(modsource)
+	sourceCache.Store(modsource, parseResult{src: src, err: err})
+	return src, err
+}
+
+// ResetSourceCache removes all the results cached by [ParseSourceCached].
+// It's useful for tests so cached results don't leak between test cases.
+func ResetSourceCache() {
+	sourceCache.Range(func(key, _ any) bool {
+		sourceCache.Delete(key)
+		return true
+	})
+}
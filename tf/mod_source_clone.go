@@ -0,0 +1,65 @@
+// Copyright 2024 Terramate GmbH
+// SPDX-License-Identifier: MPL-2.0
+
+package tf
+
+import "strconv"
+
+// CloneSpec describes the git clone of a module source package.
+type CloneSpec struct {
+	// URL is the git URL to clone.
+	URL string
+
+	// Ref is the ref to clone, if any.
+	Ref string
+
+	// Depth is the history depth of a shallow clone, as set by the depth
+	// query parameter of the source. Zero means a full clone.
+	Depth int
+
+	// Subdir is the subdir of the module inside the cloned package, if any.
+	Subdir string
+
+	// Checkout tells if Ref must be checked out after the clone, because
+	// it's a commit id and commits can't be cloned directly.
+	Checkout bool
+}
+
+// CloneSpec returns the spec for cloning the package of s. It's only
+// meaningful for git sources, so the zero value is returned for local,
+// registry and archive sources. An invalid depth query parameter is ignored.
+func (s Source) CloneSpec() CloneSpec {
+	if s.Local || s.Registry || s.Archive {
+		return CloneSpec{}
+	}
+
+	depth, err := strconv.Atoi(s.QueryValues().Get("depth"))
+	if err != nil || depth < 0 {
+		depth = 0
+	}
+	return CloneSpec{
+		URL:      s.URL,
+		Ref:      s.Ref,
+		Depth:    depth,
+		Subdir:   s.Subdir,
+		Checkout: s.RefType() == RefSHA,
+	}
+}
+
+// Args returns the git clone arguments, including the clone command, for
+// cloning the package into dir. Tags and branches are cloned with --branch,
+// while commit refs are left for a checkout after the clone. Shallow clones
+// are not done for commit refs since the commit may be missing from a
+// shallow history.
+func (spec CloneSpec) Args(dir string) []string {
+	args := []string{"clone"}
+	if !spec.Checkout {
+		if spec.Ref != "" {
+			args = append(args, "--branch", NormalizeRef(spec.Ref))
+		}
+		if spec.Depth > 0 {
+			args = append(args, "--depth", strconv.Itoa(spec.Depth))
+		}
+	}
+	return append(args, spec.URL, dir)
+}
@@ -0,0 +1,74 @@
+// Copyright 2024 Terramate GmbH
+// SPDX-License-Identifier: MPL-2.0
+
+package tf
+
+import (
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/terramate-io/terramate/errors"
+	"github.com/terramate-io/terramate/git"
+)
+
+// ErrNotGitDir indicates that a directory is not a git checkout.
+const ErrNotGitDir errors.Kind = "directory is not a git checkout"
+
+// SourceFromGitDir returns the module source of the git checkout at dir, as
+// defined by the URL of its origin remote and the checked out ref. The ref is
+// the current branch or, if HEAD is detached, the current commit id. The
+// remote URL is normalized into a git source and parsed with [ParseSource]:
+// scp-like URLs are kept, other URLs get the git:: prefix and filesystem
+// paths are converted to file URLs.
+// An error of kind [ErrNotGitDir] is returned if dir is not inside a git
+// work tree.
+func SourceFromGitDir(dir string) (Source, error) {
+	g, err := git.WithConfig(git.Config{
+		WorkingDir: dir,
+		Env:        os.Environ(),
+	})
+	if err != nil {
+		return Source{}, errors.E(err, "creating git wrapper for %q", dir)
+	}
+
+	inside, err := g.IsInsideWorkTree()
+	if err != nil {
+		return Source{}, errors.E(ErrNotGitDir, err, "checking %q", dir)
+	}
+	if !inside {
+		return Source{}, errors.E(ErrNotGitDir, "%q is not inside a git work tree", dir)
+	}
+
+	remoteURL, err := g.RemoteURL("origin")
+	if err != nil {
+		return Source{}, errors.E(err, "reading origin remote of %q", dir)
+	}
+
+	ref, err := g.CurrentBranch()
+	if err != nil {
+		ref, err = g.CurrentCommit()
+		if err != nil {
+			return Source{}, errors.E(err, "reading current ref of %q", dir)
+		}
+	}
+
+	modsource := gitRemoteSource(dir, remoteURL) + "?ref=" + url.QueryEscape(ref)
+	return ParseSource(modsource)
+}
+
+// gitRemoteSource converts the remoteURL of the git checkout at dir into a
+// git module source, without a ref.
+func gitRemoteSource(dir, remoteURL string) string {
+	switch {
+	case scpSourceRegex.MatchString(remoteURL):
+		return remoteURL
+	case strings.Contains(remoteURL, "://"):
+		return "git::" + remoteURL
+	}
+	if !filepath.IsAbs(remoteURL) {
+		remoteURL = filepath.Join(dir, remoteURL)
+	}
+	return "git::file://" + filepath.ToSlash(filepath.Clean(remoteURL))
+}
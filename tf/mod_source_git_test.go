@@ -0,0 +1,66 @@
+// Copyright 2024 Terramate GmbH
+// SPDX-License-Identifier: MPL-2.0
+
+package tf_test
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/madlambda/spells/assert"
+	"github.com/terramate-io/terramate/errors"
+	"github.com/terramate-io/terramate/test"
+	"github.com/terramate-io/terramate/test/sandbox"
+	"github.com/terramate-io/terramate/tf"
+)
+
+func TestSourceFromGitDir(t *testing.T) {
+	t.Parallel()
+
+	s := sandbox.New(t)
+	g := s.Git()
+
+	got, err := tf.SourceFromGitDir(s.RootDir())
+	assert.NoError(t, err)
+	assert.EqualStrings(t, "file://"+filepath.ToSlash(g.BareRepoAbsPath()), got.URL)
+	assert.EqualStrings(t, "file", got.PathScheme)
+	assert.EqualStrings(t, "main", got.Ref)
+
+	// the source is the same when read from a subdirectory.
+	s.RootEntry().CreateDir("sub")
+
+	for _, tc := range []struct {
+		remoteURL string
+		want      string
+	}{
+		{
+			remoteURL: "https://github.com/terramate-io/example.git",
+			want:      "github.com/terramate-io/example?ref=main",
+		},
+		{
+			remoteURL: "git@github.com:terramate-io/example.git",
+			want:      "git@github.com:terramate-io/example.git?ref=main",
+		},
+		{
+			remoteURL: "ssh://git@example.com:2222/org/vpc.git",
+			want:      "git::ssh://git@example.com:2222/org/vpc.git?ref=main",
+		},
+	} {
+		g.SetRemoteURL("origin", tc.remoteURL)
+
+		got, err := tf.SourceFromGitDir(filepath.Join(s.RootDir(), "sub"))
+		assert.NoError(t, err, "remote %q", tc.remoteURL)
+		want := test.ParseSource(t, tc.want)
+		assert.IsTrue(t, got.Equal(want), "remote %q: got %q, want %q", tc.remoteURL, got, want)
+	}
+
+	commit := g.RevParse("HEAD~1")
+	g.Checkout(commit)
+	got, err = tf.SourceFromGitDir(s.RootDir())
+	assert.NoError(t, err)
+	assert.EqualStrings(t, commit, got.Ref)
+	assert.EqualInts(t, int(tf.RefSHA), int(got.RefType()))
+
+	_, err = tf.SourceFromGitDir(test.TempDir(t))
+	assert.IsError(t, err, errors.E(tf.ErrNotGitDir))
+}
@@ -0,0 +1,176 @@
+// Copyright 2024 Terramate GmbH
+// SPDX-License-Identifier: MPL-2.0
+
+package tf
+
+import (
+	"encoding/json"
+
+	"github.com/terramate-io/terramate/errors"
+)
+
+// sourceJSON is the stable JSON representation of a [Source].
+type sourceJSON struct {
+	Raw       string `json:"raw"`
+	URL       string `json:"url,omitempty"`
+	Path      string `json:"path"`
+	Host      string `json:"host,omitempty"`
+	Scheme    string `json:"scheme,omitempty"`
+	Subdir    string `json:"subdir,omitempty"`
+	Ref       string `json:"ref,omitempty"`
+	Query     string `json:"query,omitempty"`
+	Local     bool   `json:"local"`
+	Archive   bool   `json:"archive"`
+	Registry  bool   `json:"registry"`
+	Namespace string `json:"namespace,omitempty"`
+	Name      string `json:"name,omitempty"`
+	Provider  string `json:"provider,omitempty"`
+	Version   string `json:"version,omitempty"`
+}
+
+// sourceJSONInput is a decoded [sourceJSON]. Its fields are pointers, so
+// the keys not present in the JSON can be told apart from the zero values.
+type sourceJSONInput struct {
+	Raw       *string `json:"raw"`
+	URL       *string `json:"url"`
+	Path      *string `json:"path"`
+	Host      *string `json:"host"`
+	Scheme    *string `json:"scheme"`
+	Subdir    *string `json:"subdir"`
+	Ref       *string `json:"ref"`
+	Query     *string `json:"query"`
+	Local     *bool   `json:"local"`
+	Archive   *bool   `json:"archive"`
+	Registry  *bool   `json:"registry"`
+	Namespace *string `json:"namespace"`
+	Name      *string `json:"name"`
+	Provider  *string `json:"provider"`
+	Version   *string `json:"version"`
+}
+
+// moduleSourceJSON is the JSON representation of a [ModuleSource], which is
+// the one of its [Source] with the label added.
+type moduleSourceJSON struct {
+	Label string `json:"label"`
+	sourceJSON
+}
+
+// moduleSourceJSONInput is a decoded [moduleSourceJSON].
+type moduleSourceJSONInput struct {
+	Label string `json:"label"`
+	sourceJSONInput
+}
+
+// MarshalJSON encodes s as a JSON object with one field for each of the
+// fields of [Source], so hand built sources are also kept by a round trip
+// with [Source.UnmarshalJSON].
+//
+// Beware: the raw and url fields are written as they are, so the output has
+// the credentials of the source, if any. Use [Source.Redacted] for reports
+// that must not leak them.
+func (s Source) MarshalJSON() ([]byte, error) {
+	return json.Marshal(s.toJSON())
+}
+
+// UnmarshalJSON decodes a source encoded by [Source.MarshalJSON].
+// If the raw field is set then it's parsed with [ParseSource] to compute the
+// fields not present in the JSON, so a JSON with just the raw field decodes
+// to the parsed source. The fields present in the JSON take precedence over
+// the parsed ones and the result is checked with [Source.Validate].
+// An error of kind [ErrInvalidModSrc] is returned if the JSON is malformed or
+// if it describes an invalid source.
+func (s *Source) UnmarshalJSON(data []byte) error {
+	var v sourceJSONInput
+	if err := json.Unmarshal(data, &v); err != nil {
+		return errors.E(ErrInvalidModSrc, err, "decoding module source JSON")
+	}
+	src, err := v.source()
+	if err != nil {
+		return err
+	}
+	*s = src
+	return nil
+}
+
+// MarshalJSON encodes m as the JSON object of [Source.MarshalJSON] with the
+// label field added. It's needed as the methods of the embedded [Source]
+// would otherwise be promoted and the label lost.
+func (m ModuleSource) MarshalJSON() ([]byte, error) {
+	return json.Marshal(moduleSourceJSON{
+		Label:      m.Label,
+		sourceJSON: m.Source.toJSON(),
+	})
+}
+
+// UnmarshalJSON decodes a module source encoded by [ModuleSource.MarshalJSON].
+// The source is decoded as done by [Source.UnmarshalJSON].
+func (m *ModuleSource) UnmarshalJSON(data []byte) error {
+	var v moduleSourceJSONInput
+	if err := json.Unmarshal(data, &v); err != nil {
+		return errors.E(ErrInvalidModSrc, err, "decoding module source JSON")
+	}
+	src, err := v.source()
+	if err != nil {
+		return err
+	}
+	*m = ModuleSource{Label: v.Label, Source: src}
+	return nil
+}
+
+func (s Source) toJSON() sourceJSON {
+	return sourceJSON{
+		Raw:       s.Raw,
+		URL:       s.URL,
+		Path:      s.Path,
+		Host:      s.Host,
+		Scheme:    s.PathScheme,
+		Subdir:    s.Subdir,
+		Ref:       s.Ref,
+		Query:     s.Query,
+		Local:     s.Local,
+		Archive:   s.Archive,
+		Registry:  s.Registry,
+		Namespace: s.Namespace,
+		Name:      s.Name,
+		Provider:  s.Provider,
+		Version:   s.Version,
+	}
+}
+
+func (v sourceJSONInput) source() (Source, error) {
+	var src Source
+	if v.Raw != nil && *v.Raw != "" {
+		parsed, err := ParseSource(*v.Raw)
+		if err != nil {
+			return Source{}, errors.E(ErrInvalidModSrc, err, "decoding module source JSON")
+		}
+		src = parsed
+	}
+	setJSONField(&src.Raw, v.Raw)
+	setJSONField(&src.URL, v.URL)
+	setJSONField(&src.Path, v.Path)
+	setJSONField(&src.Host, v.Host)
+	setJSONField(&src.PathScheme, v.Scheme)
+	setJSONField(&src.Subdir, v.Subdir)
+	setJSONField(&src.Ref, v.Ref)
+	setJSONField(&src.Query, v.Query)
+	setJSONField(&src.Local, v.Local)
+	setJSONField(&src.Archive, v.Archive)
+	setJSONField(&src.Registry, v.Registry)
+	setJSONField(&src.Namespace, v.Namespace)
+	setJSONField(&src.Name, v.Name)
+	setJSONField(&src.Provider, v.Provider)
+	setJSONField(&src.Version, v.Version)
+
+	if err := src.Validate(); err != nil {
+		return Source{}, errors.E(ErrInvalidModSrc, err, "decoding module source JSON")
+	}
+	return src, nil
+}
+
+// setJSONField sets *dst to *v if the field was present in the JSON.
+func setJSONField[T any](dst *T, v *T) {
+	if v != nil {
+		*dst = *v
+	}
+}
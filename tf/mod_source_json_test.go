@@ -0,0 +1,194 @@
+// Copyright 2024 Terramate GmbH
+// SPDX-License-Identifier: MPL-2.0
+
+package tf_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/madlambda/spells/assert"
+	"github.com/terramate-io/terramate/errors"
+	"github.com/terramate-io/terramate/tf"
+)
+
+func TestSourceJSONRoundTrip(t *testing.T) {
+	t.Parallel()
+
+	for _, modsource := range []string{
+		"../modules/vpc",
+		"github.com/terramate-io/example//mod?ref=v1.0.0",
+		"git@github.com:terramate-io/example.git?ref=main",
+		"git::https://example.com/vpc.git//modules/a?ref=v1&depth=1",
+		"git::ssh://git@example.com/vpc.git?ref=v1",
+		"ssh://git@example.com/vpc.git?ref=v1",
+		"git::file:///tmp/test/repo?ref=main",
+		"bitbucket.org/hashicorp/terraform-consul-aws?ref=v1",
+		"https://example.com/vpc-module.zip",
+		"hashicorp/consul/aws//modules/consul-cluster?version=0.1.0",
+		"app.terraform.io/example-corp/k8s-cluster/azurerm@1.0.0",
+	} {
+		modsource := modsource
+		t.Run(modsource, func(t *testing.T) {
+			t.Parallel()
+
+			src, err := tf.ParseSource(modsource)
+			assert.NoError(t, err)
+
+			data, err := json.Marshal(src)
+			assert.NoError(t, err)
+
+			var got tf.Source
+			assert.NoError(t, json.Unmarshal(data, &got))
+			if !got.Equal(src) {
+				t.Fatalf("round trip of %q = %#v, want %#v", data, got, src)
+			}
+			assert.EqualStrings(t, src.Raw, got.Raw)
+		})
+	}
+}
+
+func TestSourceJSONFields(t *testing.T) {
+	t.Parallel()
+
+	src, err := tf.ParseSource("github.com/terramate-io/example//mod?ref=v1")
+	assert.NoError(t, err)
+	data, err := json.Marshal(src)
+	assert.NoError(t, err)
+
+	var got map[string]any
+	assert.NoError(t, json.Unmarshal(data, &got))
+	assert.EqualInts(t, 10, len(got))
+	assert.EqualStrings(t, "github.com/terramate-io/example//mod?ref=v1", got["raw"].(string))
+	assert.EqualStrings(t, "https://github.com/terramate-io/example.git", got["url"].(string))
+	assert.EqualStrings(t, "github.com/terramate-io/example", got["path"].(string))
+	assert.EqualStrings(t, "github.com", got["host"].(string))
+	assert.EqualStrings(t, "https", got["scheme"].(string))
+	assert.EqualStrings(t, "/mod", got["subdir"].(string))
+	assert.EqualStrings(t, "v1", got["ref"].(string))
+	assert.IsTrue(t, !got["local"].(bool))
+	assert.IsTrue(t, !got["archive"].(bool))
+	assert.IsTrue(t, !got["registry"].(bool))
+}
+
+func TestSourceJSONRawOnly(t *testing.T) {
+	t.Parallel()
+
+	const modsource = "github.com/a/b//x?ref=v1"
+	want, err := tf.ParseSource(modsource)
+	assert.NoError(t, err)
+
+	var got tf.Source
+	assert.NoError(t, json.Unmarshal([]byte(`{"raw":"github.com/a/b//x?ref=v1"}`), &got))
+	if got != want {
+		t.Fatalf("raw only JSON decoded to %#v, want %#v", got, want)
+	}
+
+	// present fields still take precedence over the parsed ones.
+	got = tf.Source{}
+	assert.NoError(t, json.Unmarshal([]byte(`{"raw":"github.com/a/b//x?ref=v1","ref":"v2"}`), &got))
+	assert.EqualStrings(t, "v2", got.Ref)
+	assert.EqualStrings(t, want.URL, got.URL)
+	assert.EqualStrings(t, want.Subdir, got.Subdir)
+
+	var mod tf.ModuleSource
+	assert.NoError(t, json.Unmarshal([]byte(`{"label":"x","raw":"github.com/a/b//x?ref=v1"}`), &mod))
+	assert.EqualStrings(t, "x", mod.Label)
+	if mod.Source != want {
+		t.Fatalf("raw only JSON decoded to %#v, want %#v", mod.Source, want)
+	}
+}
+
+func TestSourceJSONHandBuiltRoundTrip(t *testing.T) {
+	t.Parallel()
+
+	for _, src := range []tf.Source{
+		{
+			URL:        "https://example.com/vpc.zip",
+			Path:       "example.com/vpc.zip",
+			Host:       "example.com",
+			PathScheme: "https",
+			Subdir:     "/mod",
+			Query:      "checksum=sha256%3Aabc",
+			Archive:    true,
+		},
+		{
+			Path:      "registry.terraform.io/hashicorp/consul/aws",
+			Host:      "registry.terraform.io",
+			Registry:  true,
+			Namespace: "hashicorp",
+			Name:      "consul",
+			Provider:  "aws",
+			Version:   "1.0.0",
+		},
+	} {
+		data, err := json.Marshal(src)
+		assert.NoError(t, err)
+
+		var got tf.Source
+		assert.NoError(t, json.Unmarshal(data, &got))
+		if got != src {
+			t.Fatalf("round trip of %s = %#v, want %#v", data, got, src)
+		}
+	}
+}
+
+func TestModuleSourceJSON(t *testing.T) {
+	t.Parallel()
+
+	sources, errs := tf.ParseSources([]byte(`
+module "vpc" {
+  source = "github.com/org/repo//vpc?ref=v1"
+}
+`))
+	assert.EqualInts(t, 0, len(errs))
+
+	data, err := json.Marshal(sources)
+	assert.NoError(t, err)
+
+	var fields []map[string]any
+	assert.NoError(t, json.Unmarshal(data, &fields))
+	assert.EqualInts(t, 1, len(fields))
+	assert.EqualStrings(t, "vpc", fields[0]["label"].(string))
+	assert.EqualStrings(t, "github.com/org/repo//vpc?ref=v1", fields[0]["raw"].(string))
+
+	var got []tf.ModuleSource
+	assert.NoError(t, json.Unmarshal(data, &got))
+	assert.EqualInts(t, 1, len(got))
+	if got[0] != sources[0] {
+		t.Fatalf("round trip of %s = %#v, want %#v", data, got[0], sources[0])
+	}
+
+	var invalid tf.ModuleSource
+	err = json.Unmarshal([]byte(`{"label": "vpc", "path": "example.com/vpc"}`), &invalid)
+	assert.IsError(t, err, errors.E(tf.ErrInvalidModSrc))
+}
+
+func TestSourceJSONInvalid(t *testing.T) {
+	t.Parallel()
+
+	for _, tc := range []struct {
+		name string
+		json string
+	}{
+		{name: "wrong field type", json: `{"raw": 1}`},
+		{name: "unparseable raw", json: `{"raw": "gcs::https://example.com/mod.zip"}`},
+		{name: "empty path", json: `{"url": "https://example.com/vpc.git"}`},
+		{name: "remote without url", json: `{"path": "example.com/vpc"}`},
+		{name: "subdir without slash", json: `{"raw": "../mod", "path": "../mod", "local": true, "subdir": "a"}`},
+		{name: "ref with whitespace", json: `{"raw": "github.com/org/repo?ref=v1", "url": "https://github.com/org/repo.git", "path": "github.com/org/repo", "ref": "v 1"}`},
+	} {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			var src tf.Source
+			err := json.Unmarshal([]byte(tc.json), &src)
+			assert.IsError(t, err, errors.E(tf.ErrInvalidModSrc))
+		})
+	}
+
+	// malformed JSON is rejected by the decoder before reaching the source.
+	var src tf.Source
+	assert.Error(t, json.Unmarshal([]byte(`{"raw":`), &src))
+}
@@ -6,6 +6,8 @@ package tf
 import (
 	"net/url"
 	"path"
+	"regexp"
+	"slices"
 	"strings"
 
 	"github.com/terramate-io/terramate/errors"
@@ -20,6 +22,11 @@ type Source struct {
 	// Eg. github.com/terramate-io/example
 	Path string
 
+	// Host is the hostname of the source, without userinfo and port.
+	// For registry sources it's the registry hostname and it's empty for
+	// local sources. Eg.: github.com
+	Host string
+
 	// PathScheme is the scheme of the path part.
 	PathScheme string
 
@@ -30,6 +37,33 @@ type Source struct {
 	// Ref is the specific reference of this source, if any.
 	Ref string
 
+	// Query is the encoded query of the source without the ref parameter, if
+	// any. The parameters are sorted by key. Eg.: depth=1
+	// It's kept encoded so [Source] values remain comparable.
+	Query string
+
+	// Local tells if the source is a local filesystem path.
+	// For local sources only the Path field is set.
+	Local bool
+
+	// Archive tells if the source is a http(s) URL of a module archive.
+	Archive bool
+
+	// Registry tells if the source is a Terraform Registry module address.
+	Registry bool
+
+	// Namespace, Name and Provider are the components of a registry module
+	// address, as defined here: https://developer.hashicorp.com/terraform/language/modules/sources#terraform-registry
+	// They are empty for non-registry sources.
+	Namespace string
+	Name      string
+	Provider  string
+
+	// Version is the version constraint of a registry source, if any. It's
+	// set from the version query parameter or from an @version annotation
+	// on the address. Eg.: hashicorp/consul/aws@1.0.0
+	Version string
+
 	// Raw source
 	Raw string
 }
@@ -40,6 +74,41 @@ const (
 
 	// ErrInvalidModSrc indicates that a module source string is invalid.
 	ErrInvalidModSrc errors.Kind = "invalid module source"
+
+	// ErrUnpinnedModSrc indicates that a module source is not pinned to an
+	// immutable ref.
+	ErrUnpinnedModSrc errors.Kind = "unpinned module source"
+)
+
+// DefaultMutableRefs are the branch names rejected by [ParseSourceStrict] if
+// no mutable refs are given.
+var DefaultMutableRefs = []string{"main", "master", "develop", "HEAD"}
+
+// DefaultGitHubHosts are the GitHub hosts used by [ParseSourceWithOptions]
+// if [ParseOptions.GitHubHosts] is not set.
+var DefaultGitHubHosts = []string{"github.com"}
+
+// ParseOptions are the options for [ParseSourceWithOptions].
+type ParseOptions struct {
+	// GitHubHosts are the hosts parsed with the GitHub shorthand semantics,
+	// eg.: a GitHub Enterprise host like github.example.com. It defaults to
+	// [DefaultGitHubHosts].
+	GitHubHosts []string
+
+	// DefaultRef is the ref set on git sources without a ref, if not empty.
+	// The [Source.Raw] field is not changed.
+	DefaultRef string
+}
+
+// DefaultRegistryHost is the host of registry sources without an explicit
+// hostname.
+const DefaultRegistryHost = "registry.terraform.io"
+
+var (
+	scpSourceRegex        = regexp.MustCompile(`^[A-Za-z0-9._-]+@[A-Za-z0-9.-]+:`)
+	registryHostRegex     = regexp.MustCompile(`^[0-9A-Za-z-]+(?:\.[0-9A-Za-z-]+)+(?::[0-9]+)?$`)
+	registryNameRegex     = regexp.MustCompile(`^[0-9A-Za-z](?:[0-9A-Za-z-_]{0,62}[0-9A-Za-z])?$`)
+	registryProviderRegex = regexp.MustCompile(`^[0-9a-z]{1,64}$`)
 )
 
 // ParseSource parses the given modsource string.
@@ -47,67 +116,217 @@ const (
 //
 // - https://www.terraform.io/language/modules/sources
 //
-// Source references that are not Git/Github are not supported.
+// Local paths, Terraform Registry addresses and http(s) archives are also
+// supported, as documented in:
+//
+// - https://developer.hashicorp.com/terraform/language/modules/sources#local-paths
+// - https://developer.hashicorp.com/terraform/language/modules/sources#terraform-registry
+// - https://developer.hashicorp.com/terraform/language/modules/sources#fetching-archives-over-http
+//
+// Other source references are not supported, unless a parser for them was
+// registered with [RegisterSourceParser].
+//
+// A subdir written after the value of the ref or version query parameters,
+// eg.: github.com/org/repo?ref=v1//mod, is also accepted and handled as
+// github.com/org/repo//mod?ref=v1. The "//" on other parameter values, like
+// a checksum URL, is kept on the value.
+//
+// A trailing slash of the package path and of the subdir is removed and so
+// are the "./" segments of the subdir, eg.: github.com/org/repo/ and
+// github.com/org/repo parse to the same [Source] and so do
+// github.com/org/repo//a/./b and github.com/org/repo//a/b.
+// The package path of Github and Bitbucket sources always has the
+// host/owner/repo form, so a repeated slash inside of it is collapsed, eg.:
+// github.com/org//repo is github.com/org/repo. In all the other places a
+// "//" is the subdir separator, which can only happen once.
+//
+// https URLs of a repository path ending in .git are git sources even without
+// the git:: prefix, eg.: https://example.com/vpc.git//mod?ref=v1
+//
+// The parsed source is checked with [Source.Validate].
 func ParseSource(modsource string) (Source, error) {
+	return ParseSourceWithOptions(modsource, ParseOptions{})
+}
+
+// ParseSourceWithOptions is like [ParseSource] but with the given options
+// applied.
+func ParseSourceWithOptions(modsource string, opts ParseOptions) (Source, error) {
+	return parseSourceWithOptions(modsource, opts, &Source{})
+}
+
+// ParseSourceInto is like [ParseSource] but the result is stored in dst.
+// The strings of dst that are equal to the ones of the parsed source are
+// reused instead of built again, so parsing a source again into the same dst,
+// like done when a file is validated again after a change, allocates less
+// than [ParseSource]. The dst is set to the zero [Source] on error.
+func ParseSourceInto(dst *Source, modsource string) error {
+	src, err := parseSourceWithOptions(modsource, ParseOptions{}, dst)
+	*dst = src
+	return err
+}
+
+// parseSourceWithOptions parses modsource as done by [ParseSourceWithOptions],
+// reusing the strings of prev equal to the ones of the result.
+func parseSourceWithOptions(modsource string, opts ParseOptions, prev *Source) (Source, error) {
+	normalized, err := normalizeQuerySubdir(modsource)
+	if err != nil {
+		return Source{}, err
+	}
+	src, err := parseSource(normalized, opts, prev)
+	if err != nil {
+		return Source{}, err
+	}
+	src.Raw = modsource
+	if src.Ref == "" && !src.Local && !src.Registry && !src.Archive {
+		src.Ref = opts.DefaultRef
+	}
+	if err := src.Validate(); err != nil {
+		return Source{}, err
+	}
+	return src, nil
+}
+
+// ParseSourceStrict parses modsource like [ParseSource] but it also fails
+// with [ErrUnpinnedModSrc] if a git source has no ref or its ref is one of the
+// mutableRefs, which defaults to [DefaultMutableRefs]. The refs are compared
+// with [NormalizeRef], so refs/heads/main is the same as main.
+// Registry sources must have a version and local and archive sources are
+// always accepted.
+func ParseSourceStrict(modsource string, mutableRefs ...string) (Source, error) {
+	src, err := ParseSource(modsource)
+	if err != nil {
+		return Source{}, err
+	}
+
+	switch {
+	case src.Local, src.Archive:
+		return src, nil
+	case src.Registry:
+		if src.Version == "" {
+			return Source{}, errors.E(ErrUnpinnedModSrc,
+				"registry source %q has no version", modsource)
+		}
+		return src, nil
+	}
+
+	if src.Ref == "" {
+		return Source{}, errors.E(ErrUnpinnedModSrc,
+			"source %q has no ref", modsource)
+	}
+
+	if len(mutableRefs) == 0 {
+		mutableRefs = DefaultMutableRefs
+	}
+	ref := src.NormalizedRef()
+	for _, mutable := range mutableRefs {
+		if ref == NormalizeRef(mutable) {
+			return Source{}, errors.E(ErrUnpinnedModSrc,
+				"source %q is pinned to the mutable ref %q", modsource, src.Ref)
+		}
+	}
+	return src, nil
+}
+
+// githubHosts returns the [ParseOptions.GitHubHosts] or the
+// [DefaultGitHubHosts] if they are not set.
+func (opts ParseOptions) githubHosts() []string {
+	if len(opts.GitHubHosts) == 0 {
+		return DefaultGitHubHosts
+	}
+	return opts.GitHubHosts
+}
+
+func parseSource(modsource string, opts ParseOptions, prev *Source) (Source, error) {
+	githubHosts := opts.githubHosts()
+
+	if suggestion, ok := suggestGitHubTreeSource(modsource, githubHosts); ok {
+		return Source{}, errors.E(ErrInvalidModSrc,
+			"source %q is a GitHub browser URL, use %q instead", modsource, suggestion)
+	}
+
 	switch {
+	case isLocalSource(modsource):
+		return parseLocalSource(modsource, prev), nil
+
 	// Github: https://developer.hashicorp.com/terraform/language/modules/sources#github
 	// Bitbucket: https://developer.hashicorp.com/terraform/language/modules/sources#bitbucket
 	// Note: mercurial is deprecated in Bitbucket so we are not supporting it in modules.
-	case strings.HasPrefix(modsource, "github.com") || strings.HasPrefix(modsource, "bitbucket.org"):
+	// Gitlab is handled the same way, with the full group path kept, since
+	// subgroups make the repository path have any number of segments.
+	// Eg.: gitlab.com/group/subgroup/repo
+	// GitHub Enterprise hosts configured in the options are handled as Github.
+	case hasHostPrefix(modsource, githubHosts...) ||
+		hasHostPrefix(modsource, "bitbucket.org", "gitlab.com"):
 		u, err := url.Parse(modsource)
 		if err != nil {
 			return Source{}, errors.E(ErrInvalidModSrc, err,
 				"%s is not a URL", modsource)
 		}
-		ref := u.Query().Get("ref")
-		subdir := parseURLSubdir(u)
+		ref, query, _ := parseRefQuery(u.RawQuery)
+		if !hasHostPrefix(modsource, "gitlab.com") {
+			u.Path = collapseRepoSlashes(u.Path)
+		}
+		subdir, err := parseURLSubdir(modsource, u, prev.Subdir)
+		if err != nil {
+			return Source{}, err
+		}
 		u.RawQuery = ""
 		u.Scheme = "https"
 		u.Path = strings.TrimSuffix(u.Path, ".git")
 
-		path := path.Join(u.Host, u.Path)
+		path := joinPath(prev.Path, u.Host, u.Path)
+		host, _, _ := strings.Cut(path, "/")
 		return Source{
 			Raw:        modsource,
-			URL:        u.String() + ".git",
+			URL:        urlString(prev.URL, u, ".git"),
 			Path:       path,
+			Host:       host,
 			PathScheme: u.Scheme,
 			Subdir:     subdir,
 			Ref:        ref,
+			Query:      query,
 		}, nil
 
-	case strings.HasPrefix(modsource, "git@"):
-		// In a git scp like url it could be any user@host, but here we are supporting
-		// the specific options allowed by Terraform on module.source:
+	case scpSourceRegex.MatchString(modsource):
+		// In a git scp like url it could be any user@host, so we don't
+		// assume Github here. Eg.: git@git.example.com:org/repo.git
 		// - https://www.terraform.io/language/modules/sources#github
-		// In this case being Github ssh access, which is always git@.
+		userHost, rawURL, _ := strings.Cut(modsource, ":")
+		_, host, _ := strings.Cut(userHost, "@")
 
-		rawURL := strings.TrimPrefix(modsource, "git@")
-
-		// This is not a valid URL given the nature of scp strings
-		// But it is enough for us to parse the query parameters
-		// and form a path that makes sense.
-		u, err := url.Parse(rawURL)
+		// This is not a valid URL given the nature of scp strings, so the
+		// path and query are split manually.
+		pathstr, rawQuery, _ := strings.Cut(rawURL, "?")
+		ref, remainingQuery, err := parseRefQuery(rawQuery)
 		if err != nil {
 			return Source{}, errors.E(ErrInvalidModSrc, err,
-				"invalid URL inside %s", modsource)
+				"invalid query inside %s", modsource)
 		}
 
-		ref := u.Query().Get("ref")
-		u.RawQuery = ""
-		pathstr, subdir := parseSubdir(u.Opaque)
-		u.Opaque = pathstr
-		pathstr = strings.TrimSuffix(path.Join(u.Scheme, u.Opaque), ".git")
+		pathstr, subdir, err := parseSubdir(modsource, pathstr, prev.Subdir)
+		if err != nil {
+			return Source{}, err
+		}
+		if strings.Trim(strings.TrimSuffix(pathstr, ".git"), "/") == "" {
+			return Source{}, errors.E(ErrInvalidModSrc,
+				"source %q is missing the repository path after %q", modsource, userHost)
+		}
 
 		return Source{
 			Raw:        modsource,
-			URL:        "git@" + u.String(),
-			Path:       pathstr,
+			URL:        concat(prev.URL, userHost, ":", pathstr),
+			Path:       joinPathTrim(prev.Path, host, pathstr, ".git"),
+			Host:       host,
 			PathScheme: "git",
 			Subdir:     subdir,
 			Ref:        ref,
+			Query:      remainingQuery,
 		}, nil
 
-	case strings.HasPrefix(modsource, "git::"):
+	// ssh:// URLs are always git repositories and so are https:// URLs of
+	// .git repositories, so the git:: prefix is optional for them.
+	case strings.HasPrefix(modsource, "git::") || strings.HasPrefix(modsource, "ssh://") ||
+		isGitHTTPSSource(modsource, githubHosts):
 		// Generic git: https://www.terraform.io/language/modules/sources#generic-git-repository
 		rawURL := strings.TrimPrefix(modsource, "git::")
 		u, err := url.Parse(rawURL)
@@ -123,47 +342,564 @@ func ParseSource(modsource string) (Source, error) {
 			)
 		}
 
-		subdir := parseURLSubdir(u)
-		// We don't want : on the pathstr. So we replace the possible :
-		// that can exist on the host.
-		pathstr := path.Join(strings.Replace(u.Host, ":", "-", -1), u.Path)
-		pathstr = strings.TrimSuffix(pathstr, ".git")
-
+		subdir, err := parseURLSubdir(modsource, u, prev.Subdir)
 		if err != nil {
 			return Source{}, err
 		}
-		ref := u.Query().Get("ref")
+		if strings.Trim(strings.TrimSuffix(u.Path, ".git"), "/") == "" {
+			return Source{}, errors.E(ErrInvalidModSrc,
+				"source %q is missing the repository path after the host", modsource)
+		}
+
+		// The path only has the hostname, the userinfo and port are only
+		// kept on the URL.
+		pathstr := joinPathTrim(prev.Path, u.Hostname(), u.Path, ".git")
+		if u.Scheme == "file" {
+			// file URLs have no host, eg.: file:///opt/mirror/modules.git
+			// so the path is the filesystem path of the repository.
+			pathstr = strings.TrimSuffix(strings.TrimPrefix(path.Clean(u.Path), "/"), ".git")
+		}
+
+		ref, query, _ := parseRefQuery(u.RawQuery)
 		u.RawQuery = ""
 		return Source{
 			Raw:        modsource,
-			URL:        u.String(),
+			URL:        urlString(prev.URL, u, ""),
 			Path:       pathstr,
+			Host:       u.Hostname(),
 			PathScheme: u.Scheme,
 			Subdir:     subdir,
 			Ref:        ref,
+			Query:      query,
 		}, nil
 
+	case isArchiveSource(modsource, githubHosts):
+		return parseArchiveSource(modsource, prev)
+
+	case isRegistrySource(modsource):
+		return parseRegistrySource(modsource, prev)
+
 	default:
+		if parse, ok := lookupSourceParser(modsource); ok {
+			return callSourceParser(parse, modsource)
+		}
 		return Source{}, errors.E(ErrUnsupportedModSrc)
 	}
 }
 
-func parseSubdir(s string) (string, string) {
+// SuggestSource returns the module source for a GitHub browser URL of a
+// directory, eg.: github.com/org/repo/tree/v1.2.0/modules/vpc is corrected to
+// github.com/org/repo//modules/vpc?ref=v1.2.0
+// The http(s):// scheme of the browser URL is optional. The ref is the path
+// segment after tree, so refs with slashes are not corrected. It returns false
+// if badSource is not a browser URL of the [DefaultGitHubHosts].
+func SuggestSource(badSource string) (string, bool) {
+	return suggestGitHubTreeSource(badSource, DefaultGitHubHosts)
+}
+
+func suggestGitHubTreeSource(modsource string, githubHosts []string) (string, bool) {
+	addr := strings.TrimPrefix(modsource, "https://")
+	addr = strings.TrimPrefix(addr, "http://")
+	if !hasHostPrefix(addr, githubHosts...) || strings.ContainsAny(addr, "?#") {
+		return "", false
+	}
+
+	// host/org/repo/tree/ref[/subdir]
+	parts := strings.SplitN(strings.TrimSuffix(addr, "/"), "/", 6)
+	if len(parts) < 5 || parts[3] != "tree" ||
+		parts[1] == "" || parts[2] == "" || parts[4] == "" {
+		return "", false
+	}
+
+	suggestion := strings.Join(parts[:3], "/")
+	if len(parts) == 6 {
+		subdir := strings.Trim(path.Clean("/"+parts[5]), "/")
+		if subdir != "" {
+			suggestion += "//" + subdir
+		}
+	}
+	return suggestion + "?ref=" + url.QueryEscape(parts[4]), true
+}
+
+// hasHostPrefix tells if the first path segment of modsource is one of the
+// hosts. Hostnames are case-insensitive, so they are compared ignoring case.
+func hasHostPrefix(modsource string, hosts ...string) bool {
+	for _, host := range hosts {
+		if len(modsource) < len(host) || !strings.EqualFold(modsource[:len(host)], host) {
+			continue
+		}
+		rest := modsource[len(host):]
+		if rest == "" || rest[0] == '/' || rest[0] == '?' {
+			return true
+		}
+	}
+	return false
+}
+
+// isHost tells if host is one of the hosts, ignoring case.
+func isHost(host string, hosts ...string) bool {
+	for _, h := range hosts {
+		if strings.EqualFold(host, h) {
+			return true
+		}
+	}
+	return false
+}
+
+// normalizeQuerySubdir moves a subdir written after the query of modsource
+// back to the end of the package path, where it's expected by the parser.
+// Sources with a subdir in both places are invalid.
+func normalizeQuerySubdir(modsource string) (string, error) {
+	if isLocalSource(modsource) {
+		return modsource, nil
+	}
+
+	addr, query, ok := strings.Cut(modsource, "?")
+	if !ok {
+		return modsource, nil
+	}
+	query, subdir, ok := cutQuerySubdir(query)
+	if !ok {
+		return modsource, nil
+	}
+
+	// The scheme separator of git:: URLs is not a subdir.
+	pkgpath := strings.TrimPrefix(addr, "git::")
+	if _, afterScheme, ok := strings.Cut(pkgpath, "://"); ok {
+		pkgpath = afterScheme
+	}
+	if strings.Contains(pkgpath, "//") {
+		return "", errors.E(ErrInvalidModSrc,
+			"source %q has a subdir both before and after the query", modsource)
+	}
+
+	normalized := addr + "//" + subdir
+	if query != "" {
+		normalized += "?" + query
+	}
+	return normalized, nil
+}
+
+// querySubdirParams are the query parameters whose value may be followed by
+// a subdir, eg.: ref=v1//mod
+// The values of other parameters, like a checksum URL or an sshkey, may have
+// a "//" of their own, so it's never a subdir separator on them.
+var querySubdirParams = []string{"ref", "version"}
+
+// cutQuerySubdir cuts the subdir written after the value of one of the
+// [querySubdirParams] of rawQuery, returning the query without it.
+// Eg.: ref=v1//mod&depth=1 gives ref=v1&depth=1 and mod.
+func cutQuerySubdir(rawQuery string) (string, string, bool) {
+	if !strings.Contains(rawQuery, "//") {
+		return rawQuery, "", false
+	}
+	params := strings.Split(rawQuery, "&")
+	for i, param := range params {
+		key, value, _ := strings.Cut(param, "=")
+		if !slices.Contains(querySubdirParams, key) {
+			continue
+		}
+		value, subdir, ok := strings.Cut(value, "//")
+		if !ok {
+			continue
+		}
+		params[i] = key + "=" + value
+		return strings.Join(params, "&"), subdir, true
+	}
+	return rawQuery, "", false
+}
+
+func isLocalSource(modsource string) bool {
+	modsource = toSlash(modsource)
+	return strings.HasPrefix(modsource, "./") ||
+		strings.HasPrefix(modsource, "../") ||
+		path.IsAbs(modsource)
+}
+
+// parseLocalSource parses a local path source. The `//` subdir syntax has no
+// special meaning for local paths, so it's just cleaned as any other path
+// separator. Relative paths keep the `./` or `../` prefix after cleaning so
+// the result is still a valid local module source, eg.: ./a/../ becomes ./
+// and .. becomes ../
+// Windows-style backslashes are converted to forward slashes, so the same
+// Path is produced in all platforms.
+func parseLocalSource(modsource string, prev *Source) Source {
+	cleaned := path.Clean(toSlash(modsource))
+	switch {
+	case cleaned == "." || cleaned == "..":
+		cleaned = concat(prev.Path, cleaned, "/")
+	case !path.IsAbs(cleaned) && !strings.HasPrefix(cleaned, "../"):
+		cleaned = concat(prev.Path, "./", cleaned)
+	}
+	return Source{
+		Raw:   modsource,
+		Path:  cleaned,
+		Local: true,
+	}
+}
+
+// toSlash replaces all the backslashes of a local path with forward slashes.
+// Differently from filepath.ToSlash it's platform independent.
+func toSlash(localpath string) string {
+	return strings.ReplaceAll(localpath, `\`, "/")
+}
+
+// isGitHTTPSSource tells if modsource is a https URL of a repository path
+// ending in .git, eg.: https://example.com/org/vpc.git//mod?ref=v1
+// Github and Bitbucket URLs are not detected, as Terraform only detects
+// their scheme-less shorthands.
+func isGitHTTPSSource(modsource string, githubHosts []string) bool {
+	rest, ok := strings.CutPrefix(modsource, "https://")
+	if !ok {
+		return false
+	}
+	addr, _, _ := strings.Cut(rest, "?")
+	pkgpath, _, _ := strings.Cut(addr, "//")
+	if hasHostPrefix(pkgpath, githubHosts...) || hasHostPrefix(pkgpath, "bitbucket.org") {
+		return false
+	}
+	pkgpath = strings.TrimRight(pkgpath, "/")
+	return strings.Contains(pkgpath, "/") && strings.HasSuffix(pkgpath, ".git")
+}
+
+// isArchiveSource tells if modsource is a http(s) URL of a .zip or .tar.gz
+// archive. Github, including the githubHosts, and Bitbucket URLs are never
+// handled as archives.
+func isArchiveSource(modsource string, githubHosts []string) bool {
+	if !strings.HasPrefix(modsource, "http://") &&
+		!strings.HasPrefix(modsource, "https://") {
+		return false
+	}
+	u, err := url.Parse(modsource)
+	if err != nil {
+		return false
+	}
+	host := u.Hostname()
+	if isHost(host, githubHosts...) || isHost(host, "bitbucket.org") {
+		return false
+	}
+	pkgpath, _, _ := strings.Cut(u.Path, "//")
+	return strings.HasSuffix(pkgpath, ".zip") || strings.HasSuffix(pkgpath, ".tar.gz")
+}
+
+func parseArchiveSource(modsource string, prev *Source) (Source, error) {
+	u, err := url.Parse(modsource)
+	if err != nil {
+		return Source{}, errors.E(ErrInvalidModSrc, err,
+			"%s is not a URL", modsource)
+	}
+
+	subdir, err := parseURLSubdir(modsource, u, prev.Subdir)
+	if err != nil {
+		return Source{}, err
+	}
+
+	// Archives have no ref, so all the query parameters, like the
+	// checksum, are kept.
+	var query string
+	if u.RawQuery != "" {
+		query = u.Query().Encode()
+	}
+	u.RawQuery = ""
+	return Source{
+		Raw:        modsource,
+		URL:        urlString(prev.URL, u, ""),
+		Path:       joinPath(prev.Path, u.Hostname(), u.Path),
+		Host:       u.Hostname(),
+		PathScheme: u.Scheme,
+		Subdir:     subdir,
+		Query:      query,
+		Archive:    true,
+	}, nil
+}
+
+// isRegistrySource tells if modsource has the shape of a registry address:
+// [<HOSTNAME>/]<NAMESPACE>/<NAME>/<PROVIDER>[@<VERSION>][//<SUBDIR>][?version=<VERSION>]
+//
+// The address is disambiguated from other sources with the rules below,
+// applied to the part before the query and the "//" subdir separator:
+//
+//   - Sources starting with "./", "../" or "/" are always local paths, as
+//     they are checked first by [ParseSource]. Eg.: ./a/b/c
+//   - Three slash separated segments are a registry address if the namespace
+//     and name only have letters, digits, "-" and "_" and the provider only
+//     has lowercase letters and digits. So no segment can have dots or be
+//     empty. Eg.: a/b/c
+//   - Four segments are a registry address if the first one is a hostname,
+//     which must have at least one dot, and the last three follow the rule
+//     above. Eg.: app.terraform.io/a/b/c
+//   - Anything else is not a registry address. Eg.: a.b/c/d, a/b/c/d and a/b
+//
+// Sources matching the Github, Bitbucket, scp-like, git::, ssh:// and archive
+// formats are handled before, so they are never registry addresses.
+func isRegistrySource(modsource string) bool {
+	addr, _, _ := strings.Cut(modsource, "?")
+	addr, _, _ = strings.Cut(addr, "//")
+	addr, _, _ = cutAddrVersion(addr)
+
+	switch strings.Count(addr, "/") {
+	case 2:
+	case 3:
+		// The hostname must be a domain name, so we don't mix a
+		// registry address with a 4-part relative path.
+		host, rest, _ := strings.Cut(addr, "/")
+		if !registryHostRegex.MatchString(host) {
+			return false
+		}
+		addr = rest
+	default:
+		return false
+	}
+
+	namespace, rest, _ := strings.Cut(addr, "/")
+	name, provider, _ := strings.Cut(rest, "/")
+	return registryNameRegex.MatchString(namespace) &&
+		registryNameRegex.MatchString(name) &&
+		registryProviderRegex.MatchString(provider)
+}
+
+func parseRegistrySource(modsource string, prev *Source) (Source, error) {
+	addr, rawQuery, _ := strings.Cut(modsource, "?")
+	var version string
+	if rawQuery != "" {
+		query, err := url.ParseQuery(rawQuery)
+		if err != nil {
+			return Source{}, errors.E(ErrInvalidModSrc, err,
+				"invalid query in registry source %q", modsource)
+		}
+
+		version = query.Get("version")
+		query.Del("version")
+		if len(query) > 0 {
+			return Source{}, errors.E(ErrInvalidModSrc,
+				"registry source %q only supports the version query parameter",
+				modsource)
+		}
+	}
+
+	addr, subdir, err := parseSubdir(modsource, addr, prev.Subdir)
+	if err != nil {
+		return Source{}, err
+	}
+	addr, addrVersion, ok := cutAddrVersion(addr)
+	if ok {
+		switch {
+		case addrVersion == "":
+			return Source{}, errors.E(ErrInvalidModSrc,
+				"registry source %q has an empty version after \"@\"", modsource)
+		case version != "":
+			return Source{}, errors.E(ErrInvalidModSrc,
+				"registry source %q has both an @version and a version query parameter",
+				modsource)
+		}
+		version = addrVersion
+	}
+	host := DefaultRegistryHost
+	if strings.Count(addr, "/") == 3 {
+		var rest string
+		host, rest, _ = strings.Cut(addr, "/")
+		host = strings.ToLower(host)
+		addr = rest
+	}
+	namespace, rest, _ := strings.Cut(addr, "/")
+	name, provider, _ := strings.Cut(rest, "/")
+
+	return Source{
+		Raw:       modsource,
+		Path:      joinPath(prev.Path, host, addr),
+		Host:      host,
+		Subdir:    subdir,
+		Registry:  true,
+		Namespace: namespace,
+		Name:      name,
+		Provider:  provider,
+		Version:   version,
+	}, nil
+}
+
+// cutAddrVersion splits the version annotation of a registry address, eg.:
+// hashicorp/consul/aws@1.0.0, returning the address without it. The version
+// is only recognized after the last path segment separator.
+func cutAddrVersion(addr string) (string, string, bool) {
+	i := strings.LastIndex(addr, "@")
+	if i < 0 || i < strings.LastIndex(addr, "/") {
+		return addr, "", false
+	}
+	return addr[:i], addr[i+1:], true
+}
+
+// parseSubdir splits the package path and the subdir of s, which is the path
+// component of the given modsource. A single trailing slash is removed from
+// both, so noisy forms like github.com/org/repo/ have the same canonical
+// result, and the subdir is cleaned with [cleanSubdir]. The prevSubdir is
+// reused if it's equal to the subdir.
+func parseSubdir(modsource, s, prevSubdir string) (string, string, error) {
 	if !strings.Contains(s, "//") {
-		return s, ""
+		return strings.TrimSuffix(s, "/"), "", nil
 	}
 
 	// From the specs we should have a single // on the path:
 	// https://www.terraform.io/language/modules/sources#modules-in-package-sub-directories
-	parsed := strings.Split(s, "//")
-	if len(parsed[1]) == 0 {
-		return parsed[0], ""
+	pkgpath, subdir, _ := strings.Cut(s, "//")
+	switch {
+	case strings.Contains(subdir, "//"):
+		return "", "", errors.E(ErrInvalidModSrc,
+			"source %q has more than one \"//\" subdir separator", modsource)
+	case pkgpath == "":
+		return "", "", errors.E(ErrInvalidModSrc,
+			"source %q is missing the package path before \"//\"", modsource)
+	case strings.HasPrefix(subdir, "/"):
+		return "", "", errors.E(ErrInvalidModSrc,
+			"source %q has a malformed subdir %q", modsource, subdir)
+	}
+	subdir = cleanSubdir(subdir)
+	if subdir == "" {
+		return "", "", errors.E(ErrInvalidModSrc,
+			"source %q has an empty subdir after \"//\"", modsource)
+	}
+	return pkgpath, concat(prevSubdir, "/", subdir), nil
+}
+
+// cleanSubdir removes the "." segments and the trailing slash of subdir, eg.:
+// a/./b/ becomes a/b. The ".." segments are kept as they are, so a subdir
+// escaping the package is not hidden by the cleaning.
+func cleanSubdir(subdir string) string {
+	subdir = strings.TrimSuffix(subdir, "/")
+	if !strings.Contains(subdir, ".") {
+		return subdir
+	}
+	segments := strings.Split(subdir, "/")
+	cleaned := segments[:0]
+	for _, segment := range segments {
+		if segment != "." {
+			cleaned = append(cleaned, segment)
+		}
+	}
+	return strings.Join(cleaned, "/")
+}
+
+// collapseRepoSlashes collapses the repeated slashes inside the host/owner/repo
+// package path of p, eg.: github.com/org//repo//mod becomes
+// github.com/org/repo//mod. The package path of Github and Bitbucket sources
+// always has these three segments, so a "//" inside of it can't be the subdir
+// separator. It's not done for Gitlab, as subgroups make its package path
+// have any number of segments.
+func collapseRepoSlashes(p string) string {
+	for {
+		i := strings.Index(p, "//")
+		if i < 0 || strings.Count(p[:i], "/") >= 2 {
+			return p
+		}
+		p = p[:i] + p[i+1:]
+	}
+}
+
+// parseRefQuery returns the ref of the rawQuery and the remaining query
+// parameters encoded. The error is the one of [url.ParseQuery], the valid
+// parameters are still returned on error as done by [url.URL.Query].
+// Queries that are empty or only have the ref are handled without building
+// the url.Values map, as they are most of the sources.
+func parseRefQuery(rawQuery string) (string, string, error) {
+	if rawQuery == "" {
+		return "", "", nil
+	}
+	if v, ok := strings.CutPrefix(rawQuery, "ref="); ok && !strings.ContainsAny(v, "&;") {
+		if ref, err := url.QueryUnescape(v); err == nil {
+			return ref, "", nil
+		}
+	}
+	query, err := url.ParseQuery(rawQuery)
+	ref := query.Get("ref")
+	query.Del("ref")
+	return ref, query.Encode(), err
+}
+
+// joinPath is like path.Join for two elements but with fewer allocations,
+// since path.Clean doesn't allocate for paths already clean. The prev path is
+// reused if it's already the joined path.
+func joinPath(prev, a, b string) string {
+	if a == "" || b == "" {
+		return path.Join(a, b)
+	}
+	// The slashes between a and b are collapsed by path.Clean anyway.
+	if concatEqual(prev, a, "/", strings.TrimLeft(b, "/")) {
+		return path.Clean(prev)
+	}
+	return path.Clean(a + "/" + b)
+}
+
+// joinPathTrim is like joinPath but suffix is trimmed from the joined path,
+// eg.: the .git suffix of a repository path.
+func joinPathTrim(prev, a, b, suffix string) string {
+	// A clean prev is still clean with the suffix appended, so it's the
+	// result if it's the joined path without the suffix.
+	if a != "" && suffix != "" && strings.HasSuffix(b, suffix) &&
+		concatEqual(prev, a, "/", strings.TrimLeft(strings.TrimSuffix(b, suffix), "/")) &&
+		path.Clean(prev) == prev {
+		return prev
+	}
+	return strings.TrimSuffix(joinPath(prev, a, b), suffix)
+}
+
+// urlString returns u.String() with suffix appended, reusing prev if it's
+// already the result. Only URLs made of the scheme, an unescaped host and the
+// path are compared with prev, the others are always built again.
+func urlString(prev string, u *url.URL, suffix string) string {
+	if prev != "" && u.Scheme != "" && u.Opaque == "" && u.User == nil &&
+		!u.OmitHost && !u.ForceQuery && u.RawQuery == "" && u.Fragment == "" &&
+		isPlainHost(u.Host) {
+		escapedPath := u.EscapedPath()
+		sep := ""
+		if escapedPath != "" && escapedPath[0] != '/' && u.Host != "" {
+			sep = "/"
+		}
+		if (u.Host != "" || escapedPath != "") &&
+			concatEqual(prev, u.Scheme, "://", u.Host, sep, escapedPath, suffix) {
+			return prev
+		}
 	}
-	return parsed[0], "/" + parsed[1]
+	return u.String() + suffix
 }
 
-func parseURLSubdir(u *url.URL) string {
-	path, subdir := parseSubdir(u.Path)
+// isPlainHost tells if host has no characters escaped by [url.URL.String].
+func isPlainHost(host string) bool {
+	for i := 0; i < len(host); i++ {
+		c := host[i]
+		switch {
+		case 'a' <= c && c <= 'z', 'A' <= c && c <= 'Z', '0' <= c && c <= '9':
+		case c == '.', c == '-', c == ':':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// concat returns the concatenation of parts, reusing prev if it's equal to
+// it, so no string is built.
+func concat(prev string, parts ...string) string {
+	if concatEqual(prev, parts...) {
+		return prev
+	}
+	return strings.Join(parts, "")
+}
+
+// concatEqual tells if s is the concatenation of parts, without building it.
+func concatEqual(s string, parts ...string) bool {
+	for _, part := range parts {
+		if !strings.HasPrefix(s, part) {
+			return false
+		}
+		s = s[len(part):]
+	}
+	return s == ""
+}
+
+func parseURLSubdir(modsource string, u *url.URL, prevSubdir string) (string, error) {
+	path, subdir, err := parseSubdir(modsource, u.Path, prevSubdir)
+	if err != nil {
+		return "", err
+	}
 	u.Path = path
-	return subdir
+	return subdir, nil
 }
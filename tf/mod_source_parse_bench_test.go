@@ -0,0 +1,50 @@
+// Copyright 2024 Terramate GmbH
+// SPDX-License-Identifier: MPL-2.0
+
+package tf_test
+
+import (
+	"testing"
+
+	"github.com/terramate-io/terramate/tf"
+)
+
+var benchSources = []string{
+	"./modules/vpc",
+	"github.com/terramate-io/example//mod?ref=v1.0.0",
+	"github.com/terramate-io/example",
+	"git@github.com:terramate-io/example.git?ref=main",
+	"git::https://example.com/vpc.git?ref=v1",
+	"git::https://example.com/vpc.git",
+	"ssh://git@example.com/vpc.git?ref=v1",
+	"https://example.com/vpc-module.zip",
+	"hashicorp/consul/aws//modules/consul-cluster?version=0.1.0",
+	"app.terraform.io/example-corp/k8s-cluster/azurerm@1.0.0",
+}
+
+func BenchmarkParseSource(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		for _, modsource := range benchSources {
+			_, err := tf.ParseSource(modsource)
+			if err != nil {
+				b.Fatal(err)
+			}
+		}
+	}
+}
+
+func BenchmarkParseSourceInto(b *testing.B) {
+	b.ReportAllocs()
+	// each source is parsed again into its own dst, as when the sources of
+	// a file are validated again after a change.
+	dsts := make([]tf.Source, len(benchSources))
+	for i := 0; i < b.N; i++ {
+		for j, modsource := range benchSources {
+			err := tf.ParseSourceInto(&dsts[j], modsource)
+			if err != nil {
+				b.Fatal(err)
+			}
+		}
+	}
+}
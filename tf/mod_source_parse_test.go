@@ -4,6 +4,9 @@
 package tf_test
 
 import (
+	"strconv"
+	"strings"
+	"sync"
 	"testing"
 
 	"github.com/madlambda/spells/assert"
@@ -33,6 +36,7 @@ func TestParseGitSources(t *testing.T) {
 				parsed: tf.Source{
 					URL:        "https://github.com/terramate-io/example.git",
 					Path:       "github.com/terramate-io/example",
+					Host:       "github.com",
 					PathScheme: "https",
 				},
 			},
@@ -44,20 +48,17 @@ func TestParseGitSources(t *testing.T) {
 				parsed: tf.Source{
 					URL:        "https://github.com/terramate-io/example.git",
 					Path:       "github.com/terramate-io/example",
+					Host:       "github.com",
 					PathScheme: "https",
 					Subdir:     "/subdir",
 				},
 			},
 		},
 		{
-			name:   "github source with empty subdir",
+			name:   "github source with empty subdir is invalid",
 			source: "github.com/terramate-io/example//",
 			want: want{
-				parsed: tf.Source{
-					URL:        "https://github.com/terramate-io/example.git",
-					Path:       "github.com/terramate-io/example",
-					PathScheme: "https",
-				},
+				err: errors.E(tf.ErrInvalidModSrc),
 			},
 		},
 		{
@@ -67,6 +68,7 @@ func TestParseGitSources(t *testing.T) {
 				parsed: tf.Source{
 					URL:        "https://github.com/terramate-io/example.git",
 					Path:       "github.com/terramate-io/example",
+					Host:       "github.com",
 					PathScheme: "https",
 				},
 			},
@@ -78,6 +80,7 @@ func TestParseGitSources(t *testing.T) {
 				parsed: tf.Source{
 					URL:        "https://github.com/terramate-io/example.git",
 					Path:       "github.com/terramate-io/example",
+					Host:       "github.com",
 					PathScheme: "https",
 					Subdir:     "/subdir/dir",
 				},
@@ -90,6 +93,7 @@ func TestParseGitSources(t *testing.T) {
 				parsed: tf.Source{
 					URL:        "https://github.com/terramate-io/example.git",
 					Path:       "github.com/terramate-io/example",
+					Host:       "github.com",
 					PathScheme: "https",
 					Ref:        "v1",
 				},
@@ -102,12 +106,221 @@ func TestParseGitSources(t *testing.T) {
 				parsed: tf.Source{
 					URL:        "https://github.com/terramate-io/example.git",
 					Path:       "github.com/terramate-io/example",
+					Host:       "github.com",
 					PathScheme: "https",
 					Subdir:     "/sub/ref",
 					Ref:        "v1",
 				},
 			},
 		},
+		{
+			name:   "github source with deeply nested subdir and ref",
+			source: "github.com/terramate-io/example//mod/sub/deep?ref=v1",
+			want: want{
+				parsed: tf.Source{
+					URL:        "https://github.com/terramate-io/example.git",
+					Path:       "github.com/terramate-io/example",
+					Host:       "github.com",
+					PathScheme: "https",
+					Subdir:     "/mod/sub/deep",
+					Ref:        "v1",
+				},
+			},
+		},
+		{
+			name:   "github source with .git suffix, nested subdir and ref",
+			source: "github.com/terramate-io/example.git//a/b/c?ref=v1",
+			want: want{
+				parsed: tf.Source{
+					URL:        "https://github.com/terramate-io/example.git",
+					Path:       "github.com/terramate-io/example",
+					Host:       "github.com",
+					PathScheme: "https",
+					Subdir:     "/a/b/c",
+					Ref:        "v1",
+				},
+			},
+		},
+		{
+			// The subdir used to be kept verbatim but the "./" segments and
+			// the trailing slash are now removed, the ".." segments are kept.
+			name:   "github source with nested subdir is cleaned",
+			source: "github.com/terramate-io/example//a/./b/../c/",
+			want: want{
+				parsed: tf.Source{
+					URL:        "https://github.com/terramate-io/example.git",
+					Path:       "github.com/terramate-io/example",
+					Host:       "github.com",
+					PathScheme: "https",
+					Subdir:     "/a/b/../c",
+				},
+			},
+		},
+		{
+			name:   "bitbucket source with deeply nested subdir and ref",
+			source: "bitbucket.org/terramate-io/example//a/b/c?ref=v1",
+			want: want{
+				parsed: tf.Source{
+					URL:        "https://bitbucket.org/terramate-io/example.git",
+					Path:       "bitbucket.org/terramate-io/example",
+					Host:       "bitbucket.org",
+					PathScheme: "https",
+					Subdir:     "/a/b/c",
+					Ref:        "v1",
+				},
+			},
+		},
+		{
+			name:   "github source with mixed-case host",
+			source: "GitHub.com/Org/Repo?ref=v1",
+			want: want{
+				parsed: tf.Source{
+					URL:        "https://GitHub.com/Org/Repo.git",
+					Path:       "GitHub.com/Org/Repo",
+					Host:       "GitHub.com",
+					PathScheme: "https",
+					Ref:        "v1",
+				},
+			},
+		},
+		{
+			name:   "github source with uppercase host",
+			source: "GITHUB.COM/org/repo//mod",
+			want: want{
+				parsed: tf.Source{
+					URL:        "https://GITHUB.COM/org/repo.git",
+					Path:       "GITHUB.COM/org/repo",
+					Host:       "GITHUB.COM",
+					PathScheme: "https",
+					Subdir:     "/mod",
+				},
+			},
+		},
+		{
+			name:   "bitbucket source with mixed-case host",
+			source: "BitBucket.org/Org/Repo?ref=v1",
+			want: want{
+				parsed: tf.Source{
+					URL:        "https://BitBucket.org/Org/Repo.git",
+					Path:       "BitBucket.org/Org/Repo",
+					Host:       "BitBucket.org",
+					PathScheme: "https",
+					Ref:        "v1",
+				},
+			},
+		},
+		{
+			name:   "github source with subdir after the ref",
+			source: "github.com/terramate-io/example?ref=v1//mod/sub",
+			want: want{
+				parsed: tf.Source{
+					URL:        "https://github.com/terramate-io/example.git",
+					Path:       "github.com/terramate-io/example",
+					Host:       "github.com",
+					PathScheme: "https",
+					Subdir:     "/mod/sub",
+					Ref:        "v1",
+				},
+			},
+		},
+		{
+			name:   "github source with subdir between query params",
+			source: "github.com/terramate-io/example?ref=v1//mod&depth=1",
+			want: want{
+				parsed: tf.Source{
+					URL:        "https://github.com/terramate-io/example.git",
+					Path:       "github.com/terramate-io/example",
+					Host:       "github.com",
+					PathScheme: "https",
+					Subdir:     "/mod",
+					Ref:        "v1",
+					Query:      "depth=1",
+				},
+			},
+		},
+		{
+			name:   "github source with subdir before and after the ref is invalid",
+			source: "github.com/terramate-io/example//mod?ref=v1//other",
+			want: want{
+				err: errors.E(tf.ErrInvalidModSrc),
+			},
+		},
+		{
+			name:   "git ssh source with an sshkey having slashes",
+			source: "git::ssh://git@example.com/vpc.git?ref=v1&sshkey=ab//cd",
+			want: want{
+				parsed: tf.Source{
+					URL:        "ssh://git@example.com/vpc.git",
+					Path:       "example.com/vpc",
+					Host:       "example.com",
+					PathScheme: "ssh",
+					Ref:        "v1",
+					Query:      "sshkey=ab%2F%2Fcd",
+				},
+			},
+		},
+		{
+			name:   "git ssh source with an sshkey having slashes and subdir after the ref",
+			source: "git::ssh://git@example.com/vpc.git?sshkey=ab//cd&ref=v1//mod",
+			want: want{
+				parsed: tf.Source{
+					URL:        "ssh://git@example.com/vpc.git",
+					Path:       "example.com/vpc",
+					Host:       "example.com",
+					PathScheme: "ssh",
+					Subdir:     "/mod",
+					Ref:        "v1",
+					Query:      "sshkey=ab%2F%2Fcd",
+				},
+			},
+		},
+		{
+			name:   "github source with empty subdir after the ref is invalid",
+			source: "github.com/terramate-io/example?ref=v1//",
+			want: want{
+				err: errors.E(tf.ErrInvalidModSrc),
+			},
+		},
+		{
+			name:   "gitlab source with subdir and ref",
+			source: "gitlab.com/acme/infra//vpc?ref=v1",
+			want: want{
+				parsed: tf.Source{
+					URL:        "https://gitlab.com/acme/infra.git",
+					Path:       "gitlab.com/acme/infra",
+					Host:       "gitlab.com",
+					PathScheme: "https",
+					Subdir:     "/vpc",
+					Ref:        "v1",
+				},
+			},
+		},
+		{
+			name:   "gitlab source with subgroup",
+			source: "gitlab.com/group/subgroup/repo",
+			want: want{
+				parsed: tf.Source{
+					URL:        "https://gitlab.com/group/subgroup/repo.git",
+					Path:       "gitlab.com/group/subgroup/repo",
+					Host:       "gitlab.com",
+					PathScheme: "https",
+				},
+			},
+		},
+		{
+			name:   "gitlab source with nested subgroups, .git suffix, subdir and ref",
+			source: "gitlab.com/group/sub1/sub2/repo.git//modules/vpc?ref=v2",
+			want: want{
+				parsed: tf.Source{
+					URL:        "https://gitlab.com/group/sub1/sub2/repo.git",
+					Path:       "gitlab.com/group/sub1/sub2/repo",
+					Host:       "gitlab.com",
+					PathScheme: "https",
+					Subdir:     "/modules/vpc",
+					Ref:        "v2",
+				},
+			},
+		},
 		{
 			name:   "github source with ref and .git suffix",
 			source: "github.com/terramate-io/example.git?ref=v1",
@@ -115,6 +328,7 @@ func TestParseGitSources(t *testing.T) {
 				parsed: tf.Source{
 					URL:        "https://github.com/terramate-io/example.git",
 					Path:       "github.com/terramate-io/example",
+					Host:       "github.com",
 					PathScheme: "https",
 					Ref:        "v1",
 				},
@@ -127,6 +341,7 @@ func TestParseGitSources(t *testing.T) {
 				parsed: tf.Source{
 					URL:        "https://github.com/terramate-io/example.git",
 					Path:       "github.com/terramate-io/example",
+					Host:       "github.com",
 					PathScheme: "https",
 					Subdir:     "/dir",
 					Ref:        "v1",
@@ -134,13 +349,15 @@ func TestParseGitSources(t *testing.T) {
 			},
 		},
 		{
-			name:   "github source with unknown query param ignored",
+			name:   "github source with unknown query param retained",
 			source: "github.com/terramate-io/example?key=v1",
 			want: want{
 				parsed: tf.Source{
 					URL:        "https://github.com/terramate-io/example.git",
 					Path:       "github.com/terramate-io/example",
+					Host:       "github.com",
 					PathScheme: "https",
+					Query:      "key=v1",
 				},
 			},
 		},
@@ -151,6 +368,7 @@ func TestParseGitSources(t *testing.T) {
 				parsed: tf.Source{
 					URL:        "git@github.com:terramate-io/example.git",
 					Path:       "github.com/terramate-io/example",
+					Host:       "github.com",
 					PathScheme: "git",
 				},
 			},
@@ -162,22 +380,58 @@ func TestParseGitSources(t *testing.T) {
 				parsed: tf.Source{
 					URL:        "git@github.com:terramate-io/example.git",
 					Path:       "github.com/terramate-io/example",
+					Host:       "github.com",
 					PathScheme: "git",
 					Subdir:     "/subdir",
 				},
 			},
 		},
 		{
-			name:   "git@ source with empty subdir",
-			source: "git@github.com:terramate-io/example.git//",
+			name:   "git@ source without .git suffix",
+			source: "git@github.com:terramate-io/example",
 			want: want{
 				parsed: tf.Source{
-					URL:        "git@github.com:terramate-io/example.git",
+					URL:        "git@github.com:terramate-io/example",
+					Path:       "github.com/terramate-io/example",
+					Host:       "github.com",
+					PathScheme: "git",
+				},
+			},
+		},
+		{
+			name:   "git@ source without .git suffix and with subdir",
+			source: "git@github.com:terramate-io/example//subdir",
+			want: want{
+				parsed: tf.Source{
+					URL:        "git@github.com:terramate-io/example",
 					Path:       "github.com/terramate-io/example",
+					Host:       "github.com",
 					PathScheme: "git",
+					Subdir:     "/subdir",
+				},
+			},
+		},
+		{
+			name:   "git@ source without .git suffix and with subdir and ref",
+			source: "git@github.com:terramate-io/example//sub/dir?ref=v2",
+			want: want{
+				parsed: tf.Source{
+					URL:        "git@github.com:terramate-io/example",
+					Path:       "github.com/terramate-io/example",
+					Host:       "github.com",
+					PathScheme: "git",
+					Subdir:     "/sub/dir",
+					Ref:        "v2",
 				},
 			},
 		},
+		{
+			name:   "git@ source with empty subdir is invalid",
+			source: "git@github.com:terramate-io/example.git//",
+			want: want{
+				err: errors.E(tf.ErrInvalidModSrc),
+			},
+		},
 		{
 			name:   "git@ source with ref",
 			source: "git@github.com:terramate-io/example.git?ref=v2",
@@ -185,6 +439,7 @@ func TestParseGitSources(t *testing.T) {
 				parsed: tf.Source{
 					URL:        "git@github.com:terramate-io/example.git",
 					Path:       "github.com/terramate-io/example",
+					Host:       "github.com",
 					PathScheme: "git",
 					Ref:        "v2",
 				},
@@ -197,6 +452,7 @@ func TestParseGitSources(t *testing.T) {
 				parsed: tf.Source{
 					URL:        "git@github.com:terramate-io/example.git",
 					Path:       "github.com/terramate-io/example",
+					Host:       "github.com",
 					PathScheme: "git",
 					Subdir:     "/sub/dir",
 					Ref:        "v2",
@@ -204,13 +460,67 @@ func TestParseGitSources(t *testing.T) {
 			},
 		},
 		{
-			name:   "git@ source with unknown query param ignored",
+			name:   "git@ source with unknown query param retained",
 			source: "git@github.com:terramate-io/example.git?key=v2",
 			want: want{
 				parsed: tf.Source{
 					URL:        "git@github.com:terramate-io/example.git",
 					Path:       "github.com/terramate-io/example",
+					Host:       "github.com",
 					PathScheme: "git",
+					Query:      "key=v2",
+				},
+			},
+		},
+		{
+			name:   "scp source with non-github host",
+			source: "git@git.internal.acme:platform/modules.git//vpc?ref=v2",
+			want: want{
+				parsed: tf.Source{
+					URL:        "git@git.internal.acme:platform/modules.git",
+					Path:       "git.internal.acme/platform/modules",
+					Host:       "git.internal.acme",
+					PathScheme: "git",
+					Subdir:     "/vpc",
+					Ref:        "v2",
+				},
+			},
+		},
+		{
+			name:   "scp source with non-git user",
+			source: "gitea@git.internal.acme:platform/modules.git",
+			want: want{
+				parsed: tf.Source{
+					URL:        "gitea@git.internal.acme:platform/modules.git",
+					Path:       "git.internal.acme/platform/modules",
+					Host:       "git.internal.acme",
+					PathScheme: "git",
+				},
+			},
+		},
+		{
+			name:   "scp source with IP host",
+			source: "git@10.0.0.1:platform/modules.git?ref=v1",
+			want: want{
+				parsed: tf.Source{
+					URL:        "git@10.0.0.1:platform/modules.git",
+					Path:       "10.0.0.1/platform/modules",
+					Host:       "10.0.0.1",
+					PathScheme: "git",
+					Ref:        "v1",
+				},
+			},
+		},
+		{
+			name:   "scp source with nested path",
+			source: "git@gitlab.com:group/subgroup/repo.git//mod",
+			want: want{
+				parsed: tf.Source{
+					URL:        "git@gitlab.com:group/subgroup/repo.git",
+					Path:       "gitlab.com/group/subgroup/repo",
+					Host:       "gitlab.com",
+					PathScheme: "git",
+					Subdir:     "/mod",
 				},
 			},
 		},
@@ -221,6 +531,7 @@ func TestParseGitSources(t *testing.T) {
 				parsed: tf.Source{
 					URL:        "https://example.com/vpc.git",
 					Path:       "example.com/vpc",
+					Host:       "example.com",
 					PathScheme: "https",
 				},
 			},
@@ -232,20 +543,17 @@ func TestParseGitSources(t *testing.T) {
 				parsed: tf.Source{
 					URL:        "https://example.com/vpc.git",
 					Path:       "example.com/vpc",
+					Host:       "example.com",
 					PathScheme: "https",
 					Subdir:     "/subdir",
 				},
 			},
 		},
 		{
-			name:   "git::https source with empty subdir",
+			name:   "git::https source with empty subdir is invalid",
 			source: "git::https://example.com/vpc.git//",
 			want: want{
-				parsed: tf.Source{
-					URL:        "https://example.com/vpc.git",
-					Path:       "example.com/vpc",
-					PathScheme: "https",
-				},
+				err: errors.E(tf.ErrInvalidModSrc),
 			},
 		},
 		{
@@ -254,7 +562,7 @@ func TestParseGitSources(t *testing.T) {
 			want: want{
 				parsed: tf.Source{
 					URL:        "file:///tmp/test/repo",
-					Path:       "/tmp/test/repo",
+					Path:       "tmp/test/repo",
 					PathScheme: "file",
 					Subdir:     "/subdir",
 				},
@@ -267,6 +575,7 @@ func TestParseGitSources(t *testing.T) {
 				parsed: tf.Source{
 					URL:        "https://example.com/vpc.git",
 					Path:       "example.com/vpc",
+					Host:       "example.com",
 					PathScheme: "https",
 					Ref:        "v3",
 				},
@@ -279,19 +588,36 @@ func TestParseGitSources(t *testing.T) {
 				parsed: tf.Source{
 					URL:        "https://example.com/vpc.git",
 					Path:       "example.com/vpc",
+					Host:       "example.com",
 					PathScheme: "https",
 					Subdir:     "/sub/dir",
 					Ref:        "v3",
 				},
 			},
 		},
+		{
+			name:   "git::https source with ref and depth",
+			source: "git::https://example.com/infra.git//modules/net?ref=main&depth=1",
+			want: want{
+				parsed: tf.Source{
+					URL:        "https://example.com/infra.git",
+					Path:       "example.com/infra",
+					Host:       "example.com",
+					PathScheme: "https",
+					Subdir:     "/modules/net",
+					Ref:        "main",
+					Query:      "depth=1",
+				},
+			},
+		},
 		{
 			name:   "git::https source with port",
 			source: "git::https://example.com:443/vpc.git?ref=v3",
 			want: want{
 				parsed: tf.Source{
 					URL:        "https://example.com:443/vpc.git",
-					Path:       "example.com-443/vpc",
+					Path:       "example.com/vpc",
+					Host:       "example.com",
 					PathScheme: "https",
 					Ref:        "v3",
 				},
@@ -303,7 +629,8 @@ func TestParseGitSources(t *testing.T) {
 			want: want{
 				parsed: tf.Source{
 					URL:        "https://example.com:443/vpc.git",
-					Path:       "example.com-443/vpc",
+					Path:       "example.com/vpc",
+					Host:       "example.com",
 					PathScheme: "https",
 					Subdir:     "/port/dir",
 					Ref:        "v3",
@@ -311,13 +638,15 @@ func TestParseGitSources(t *testing.T) {
 			},
 		},
 		{
-			name:   "git::https source with unknown query param ignored",
+			name:   "git::https source with unknown query param retained",
 			source: "git::https://example.com/vpc.git?key=v3",
 			want: want{
 				parsed: tf.Source{
 					URL:        "https://example.com/vpc.git",
 					Path:       "example.com/vpc",
+					Host:       "example.com",
 					PathScheme: "https",
+					Query:      "key=v3",
 				},
 			},
 		},
@@ -328,10 +657,47 @@ func TestParseGitSources(t *testing.T) {
 				parsed: tf.Source{
 					URL:        "ssh://username@example.com/storage.git",
 					Path:       "example.com/storage",
+					Host:       "example.com",
 					PathScheme: "ssh",
 				},
 			},
 		},
+		{
+			name:   "git::file source",
+			source: "git::file:///opt/mirror/modules.git",
+			want: want{
+				parsed: tf.Source{
+					URL:        "file:///opt/mirror/modules.git",
+					Path:       "opt/mirror/modules",
+					PathScheme: "file",
+				},
+			},
+		},
+		{
+			name:   "git::file source with subdir and ref",
+			source: "git::file:///opt/mirror/modules.git//vpc?ref=v1",
+			want: want{
+				parsed: tf.Source{
+					URL:        "file:///opt/mirror/modules.git",
+					Path:       "opt/mirror/modules",
+					PathScheme: "file",
+					Subdir:     "/vpc",
+					Ref:        "v1",
+				},
+			},
+		},
+		{
+			name:   "git::file source with ref and without .git suffix",
+			source: "git::file:///opt/mirror/modules?ref=v1",
+			want: want{
+				parsed: tf.Source{
+					URL:        "file:///opt/mirror/modules",
+					Path:       "opt/mirror/modules",
+					PathScheme: "file",
+					Ref:        "v1",
+				},
+			},
+		},
 		{
 			name:   "git::ssh source and subdir",
 			source: "git::ssh://username@example.com/storage.git//subdir",
@@ -339,20 +705,17 @@ func TestParseGitSources(t *testing.T) {
 				parsed: tf.Source{
 					URL:        "ssh://username@example.com/storage.git",
 					Path:       "example.com/storage",
+					Host:       "example.com",
 					PathScheme: "ssh",
 					Subdir:     "/subdir",
 				},
 			},
 		},
 		{
-			name:   "git::ssh source and empty subdir",
+			name:   "git::ssh source and empty subdir is invalid",
 			source: "git::ssh://username@example.com/storage.git//",
 			want: want{
-				parsed: tf.Source{
-					URL:        "ssh://username@example.com/storage.git",
-					Path:       "example.com/storage",
-					PathScheme: "ssh",
-				},
+				err: errors.E(tf.ErrInvalidModSrc),
 			},
 		},
 		{
@@ -361,7 +724,8 @@ func TestParseGitSources(t *testing.T) {
 			want: want{
 				parsed: tf.Source{
 					URL:        "ssh://username@example.com:666/storage.git",
-					Path:       "example.com-666/storage",
+					Path:       "example.com/storage",
+					Host:       "example.com",
 					PathScheme: "ssh",
 				},
 			},
@@ -372,69 +736,564 @@ func TestParseGitSources(t *testing.T) {
 			want: want{
 				parsed: tf.Source{
 					URL:        "ssh://username@example.com:666/storage.git",
-					Path:       "example.com-666/storage",
+					Path:       "example.com/storage",
+					Host:       "example.com",
 					PathScheme: "ssh",
 					Subdir:     "/ssh/dir",
 				},
 			},
 		},
 		{
-			name:   "git::ssh source with ref",
-			source: "git::ssh://username@example.com/storage.git?ref=v4",
+			name:   "git::ssh source with user, port, subdir and ref",
+			source: "git::ssh://git@gitlab.acme.com:2222/infra/modules.git//network?ref=main",
 			want: want{
 				parsed: tf.Source{
-					URL:        "ssh://username@example.com/storage.git",
-					Path:       "example.com/storage",
+					URL:        "ssh://git@gitlab.acme.com:2222/infra/modules.git",
+					Path:       "gitlab.acme.com/infra/modules",
+					Host:       "gitlab.acme.com",
 					PathScheme: "ssh",
-					Ref:        "v4",
+					Subdir:     "/network",
+					Ref:        "main",
 				},
 			},
 		},
 		{
-			name:   "git::ssh source with ref and subdir",
-			source: "git::ssh://username@example.com/storage.git//sub/ref?ref=v4",
+			name:   "ssh source without git:: prefix",
+			source: "ssh://git@github.com/org/repo.git",
 			want: want{
 				parsed: tf.Source{
-					URL:        "ssh://username@example.com/storage.git",
-					Path:       "example.com/storage",
+					URL:        "ssh://git@github.com/org/repo.git",
+					Path:       "github.com/org/repo",
+					Host:       "github.com",
 					PathScheme: "ssh",
-					Subdir:     "/sub/ref",
-					Ref:        "v4",
 				},
 			},
 		},
 		{
-			name:   "git::ssh source with unknown query param ignored",
-			source: "git::ssh://username@example.com/storage.git?key=v4",
+			name:   "ssh source without git:: prefix with subdir and ref",
+			source: "ssh://git@github.com/org/repo.git//mod?ref=v1",
 			want: want{
 				parsed: tf.Source{
-					URL:        "ssh://username@example.com/storage.git",
-					Path:       "example.com/storage",
+					URL:        "ssh://git@github.com/org/repo.git",
+					Path:       "github.com/org/repo",
+					Host:       "github.com",
 					PathScheme: "ssh",
+					Subdir:     "/mod",
+					Ref:        "v1",
 				},
 			},
 		},
 		{
-			name:   "bitbucket.org URLs are supported",
-			source: "bitbucket.org/hashicorp/terraform-consul-aws",
+			name:   "ssh source without git:: prefix with port, subdir and ref",
+			source: "ssh://git@gitlab.acme.com:2222/infra/modules.git//network/vpc?ref=v1&depth=1",
 			want: want{
 				parsed: tf.Source{
-					URL:        "https://bitbucket.org/hashicorp/terraform-consul-aws.git",
-					Path:       "bitbucket.org/hashicorp/terraform-consul-aws",
-					PathScheme: "https",
+					URL:        "ssh://git@gitlab.acme.com:2222/infra/modules.git",
+					Path:       "gitlab.acme.com/infra/modules",
+					Host:       "gitlab.acme.com",
+					PathScheme: "ssh",
+					Subdir:     "/network/vpc",
+					Ref:        "v1",
+					Query:      "depth=1",
 				},
 			},
 		},
 		{
-			name:   "local is not supported",
-			source: "./vpc-module.zip",
+			name:   "ssh source without git:: prefix with subdir after the ref",
+			source: "ssh://git@example.com:2222/storage.git?ref=v1//sub",
+			want: want{
+				parsed: tf.Source{
+					URL:        "ssh://git@example.com:2222/storage.git",
+					Path:       "example.com/storage",
+					Host:       "example.com",
+					PathScheme: "ssh",
+					Subdir:     "/sub",
+					Ref:        "v1",
+				},
+			},
+		},
+		{
+			name:   "https .git source without git:: prefix",
+			source: "https://example.com/org/vpc.git",
+			want: want{
+				parsed: tf.Source{
+					URL:        "https://example.com/org/vpc.git",
+					Path:       "example.com/org/vpc",
+					Host:       "example.com",
+					PathScheme: "https",
+				},
+			},
+		},
+		{
+			name:   "https .git source without git:: prefix with subdir and ref",
+			source: "https://example.com/org/vpc.git//modules/a?ref=v1&depth=1",
+			want: want{
+				parsed: tf.Source{
+					URL:        "https://example.com/org/vpc.git",
+					Path:       "example.com/org/vpc",
+					Host:       "example.com",
+					PathScheme: "https",
+					Subdir:     "/modules/a",
+					Ref:        "v1",
+					Query:      "depth=1",
+				},
+			},
+		},
+		{
+			name:   "https .git source without git:: prefix with userinfo and port",
+			source: "https://user@git.acme.com:8443/infra/vpc.git?ref=v2",
+			want: want{
+				parsed: tf.Source{
+					URL:        "https://user@git.acme.com:8443/infra/vpc.git",
+					Path:       "git.acme.com/infra/vpc",
+					Host:       "git.acme.com",
+					PathScheme: "https",
+					Ref:        "v2",
+				},
+			},
+		},
+		{
+			name:   "https github .git source without git:: prefix is not supported",
+			source: "https://github.com/org/repo.git",
+			want: want{
+				err: errors.E(tf.ErrUnsupportedModSrc),
+			},
+		},
+		{
+			name:   "http .git source without git:: prefix is not supported",
+			source: "http://example.com/org/vpc.git",
+			want: want{
+				err: errors.E(tf.ErrUnsupportedModSrc),
+			},
+		},
+		{
+			name:   "https archive of a .git path is still an archive",
+			source: "https://example.com/org/vpc.git.zip",
+			want: want{
+				parsed: tf.Source{
+					URL:        "https://example.com/org/vpc.git.zip",
+					Path:       "example.com/org/vpc.git.zip",
+					Host:       "example.com",
+					PathScheme: "https",
+					Archive:    true,
+				},
+			},
+		},
+		{
+			name:   "git::http source with port",
+			source: "git::http://example.com:8080/vpc.git//dir?ref=v3",
+			want: want{
+				parsed: tf.Source{
+					URL:        "http://example.com:8080/vpc.git",
+					Path:       "example.com/vpc",
+					Host:       "example.com",
+					PathScheme: "http",
+					Subdir:     "/dir",
+					Ref:        "v3",
+				},
+			},
+		},
+		{
+			name:   "git::https source with subdir after the ref",
+			source: "git::https://example.com/vpc.git?ref=v3//dir",
+			want: want{
+				parsed: tf.Source{
+					URL:        "https://example.com/vpc.git",
+					Path:       "example.com/vpc",
+					Host:       "example.com",
+					PathScheme: "https",
+					Subdir:     "/dir",
+					Ref:        "v3",
+				},
+			},
+		},
+		{
+			name:   "git::https source with subdir before and after the ref is invalid",
+			source: "git::https://example.com/vpc.git//dir?ref=v3//dir",
+			want: want{
+				err: errors.E(tf.ErrInvalidModSrc),
+			},
+		},
+		{
+			name:   "git@ source with subdir after the ref",
+			source: "git@github.com:terramate-io/example.git?ref=v2//sub/dir",
+			want: want{
+				parsed: tf.Source{
+					URL:        "git@github.com:terramate-io/example.git",
+					Path:       "github.com/terramate-io/example",
+					Host:       "github.com",
+					PathScheme: "git",
+					Subdir:     "/sub/dir",
+					Ref:        "v2",
+				},
+			},
+		},
+		{
+			name:   "git::https source with user and port",
+			source: "git::https://user@example.com:8443/vpc.git",
+			want: want{
+				parsed: tf.Source{
+					URL:        "https://user@example.com:8443/vpc.git",
+					Path:       "example.com/vpc",
+					Host:       "example.com",
+					PathScheme: "https",
+				},
+			},
+		},
+		{
+			name:   "git::ssh source with ref",
+			source: "git::ssh://username@example.com/storage.git?ref=v4",
+			want: want{
+				parsed: tf.Source{
+					URL:        "ssh://username@example.com/storage.git",
+					Path:       "example.com/storage",
+					Host:       "example.com",
+					PathScheme: "ssh",
+					Ref:        "v4",
+				},
+			},
+		},
+		{
+			name:   "git::ssh source with ref and subdir",
+			source: "git::ssh://username@example.com/storage.git//sub/ref?ref=v4",
+			want: want{
+				parsed: tf.Source{
+					URL:        "ssh://username@example.com/storage.git",
+					Path:       "example.com/storage",
+					Host:       "example.com",
+					PathScheme: "ssh",
+					Subdir:     "/sub/ref",
+					Ref:        "v4",
+				},
+			},
+		},
+		{
+			name:   "git::ssh source with unknown query param retained",
+			source: "git::ssh://username@example.com/storage.git?key=v4",
+			want: want{
+				parsed: tf.Source{
+					URL:        "ssh://username@example.com/storage.git",
+					Path:       "example.com/storage",
+					Host:       "example.com",
+					PathScheme: "ssh",
+					Query:      "key=v4",
+				},
+			},
+		},
+		{
+			name:   "github source with multiple subdir separators is invalid",
+			source: "github.com/terramate-io/example//sub//dir?ref=v1",
+			want: want{
+				err: errors.E(tf.ErrInvalidModSrc),
+			},
+		},
+		{
+			name:   "github source with malformed subdir separator is invalid",
+			source: "github.com/terramate-io/example///sub",
+			want: want{
+				err: errors.E(tf.ErrInvalidModSrc),
+			},
+		},
+		{
+			name:   "github source with subdir escaping the package is invalid",
+			source: "github.com/a/b//../../etc",
+			want: want{
+				err: errors.E(tf.ErrInvalidModSrc),
+			},
+		},
+		{
+			name:   "git source with nested subdir escaping the package is invalid",
+			source: "git::https://example.com/vpc.git//a/../../etc?ref=v1",
+			want: want{
+				err: errors.E(tf.ErrInvalidModSrc),
+			},
+		},
+		{
+			name:   "scp source without repository path is invalid",
+			source: "git@example.com:",
+			want: want{
+				err: errors.E(tf.ErrInvalidModSrc),
+			},
+		},
+		{
+			name:   "scp source with only .git as repository path is invalid",
+			source: "git@example.com:.git?ref=v1",
+			want: want{
+				err: errors.E(tf.ErrInvalidModSrc),
+			},
+		},
+		{
+			name:   "https source with only .git as repository path is invalid",
+			source: "https://example.com/.git",
+			want: want{
+				err: errors.E(tf.ErrInvalidModSrc),
+			},
+		},
+		{
+			name:   "git::https source with only a slash as path is invalid",
+			source: "git::https://example.com/?ref=v1",
+			want: want{
+				err: errors.E(tf.ErrInvalidModSrc),
+			},
+		},
+		{
+			name:   "git::https source with subdir separator after host is invalid",
+			source: "git::https://example.com//sub",
+			want: want{
+				err: errors.E(tf.ErrInvalidModSrc),
+			},
+		},
+		{
+			name:   "git::https source with multiple subdir separators is invalid",
+			source: "git::https://example.com/vpc.git//a//b",
+			want: want{
+				err: errors.E(tf.ErrInvalidModSrc),
+			},
+		},
+		{
+			name:   "git@ source with multiple subdir separators is invalid",
+			source: "git@github.com:terramate-io/example.git//a//b",
+			want: want{
+				err: errors.E(tf.ErrInvalidModSrc),
+			},
+		},
+		{
+			name:   "registry source with empty subdir is invalid",
+			source: "hashicorp/consul/aws//",
+			want: want{
+				err: errors.E(tf.ErrInvalidModSrc),
+			},
+		},
+		{
+			name:   "bitbucket.org URLs are supported",
+			source: "bitbucket.org/hashicorp/terraform-consul-aws",
+			want: want{
+				parsed: tf.Source{
+					URL:        "https://bitbucket.org/hashicorp/terraform-consul-aws.git",
+					Path:       "bitbucket.org/hashicorp/terraform-consul-aws",
+					Host:       "bitbucket.org",
+					PathScheme: "https",
+				},
+			},
+		},
+		{
+			name:   "local source",
+			source: "./vpc",
+			want: want{
+				parsed: tf.Source{
+					Path:  "./vpc",
+					Local: true,
+				},
+			},
+		},
+		{
+			name:   "local source is cleaned",
+			source: "./modules/../vpc/",
+			want: want{
+				parsed: tf.Source{
+					Path:  "./vpc",
+					Local: true,
+				},
+			},
+		},
+		{
+			name:   "local source in parent dir",
+			source: "../modules/vpc",
+			want: want{
+				parsed: tf.Source{
+					Path:  "../modules/vpc",
+					Local: true,
+				},
+			},
+		},
+		{
+			name:   "local source cleaned into parent dir",
+			source: "./a/../../modules/vpc",
+			want: want{
+				parsed: tf.Source{
+					Path:  "../modules/vpc",
+					Local: true,
+				},
+			},
+		},
+		{
+			name:   "local source has no subdir",
+			source: "./modules//vpc",
+			want: want{
+				parsed: tf.Source{
+					Path:  "./modules/vpc",
+					Local: true,
+				},
+			},
+		},
+		{
+			name:   "absolute local source",
+			source: "/modules/../vpc",
+			want: want{
+				parsed: tf.Source{
+					Path:  "/vpc",
+					Local: true,
+				},
+			},
+		},
+		{
+			name:   "absolute local source cannot escape root",
+			source: "/../../vpc",
+			want: want{
+				parsed: tf.Source{
+					Path:  "/vpc",
+					Local: true,
+				},
+			},
+		},
+		{
+			name:   "local source with backslashes",
+			source: `.\modules\vpc`,
+			want: want{
+				parsed: tf.Source{
+					Path:  "./modules/vpc",
+					Local: true,
+				},
+			},
+		},
+		{
+			name:   "local source in parent dir with backslashes",
+			source: `..\..\modules\vpc\`,
+			want: want{
+				parsed: tf.Source{
+					Path:  "../../modules/vpc",
+					Local: true,
+				},
+			},
+		},
+		{
+			name:   "local source with mixed separators",
+			source: `./modules\net/..\vpc`,
+			want: want{
+				parsed: tf.Source{
+					Path:  "./modules/vpc",
+					Local: true,
+				},
+			},
+		},
+		{
+			name:   "absolute local source with backslashes",
+			source: `\modules\vpc`,
+			want: want{
+				parsed: tf.Source{
+					Path:  "/modules/vpc",
+					Local: true,
+				},
+			},
+		},
+		{
+			name:   "remote source backslashes are not converted",
+			source: `git::https://example.com/vpc.git//sub\dir`,
+			want: want{
+				parsed: tf.Source{
+					URL:        "https://example.com/vpc.git",
+					Path:       "example.com/vpc",
+					Host:       "example.com",
+					PathScheme: "https",
+					Subdir:     `/sub\dir`,
+				},
+			},
+		},
+		{
+			name:   "https archive source",
+			source: "https://example.com/vpc-module.zip",
+			want: want{
+				parsed: tf.Source{
+					URL:        "https://example.com/vpc-module.zip",
+					Path:       "example.com/vpc-module.zip",
+					Host:       "example.com",
+					PathScheme: "https",
+					Archive:    true,
+				},
+			},
+		},
+		{
+			name:   "https archive source with subdir",
+			source: "https://artifacts.acme.com/modules/vpc-1.2.0.zip//vpc",
+			want: want{
+				parsed: tf.Source{
+					URL:        "https://artifacts.acme.com/modules/vpc-1.2.0.zip",
+					Path:       "artifacts.acme.com/modules/vpc-1.2.0.zip",
+					Host:       "artifacts.acme.com",
+					PathScheme: "https",
+					Subdir:     "/vpc",
+					Archive:    true,
+				},
+			},
+		},
+		{
+			name:   "http tar.gz archive source with subdir and checksum",
+			source: "http://artifacts.acme.com:8080/modules/vpc.tar.gz//mod/vpc?checksum=sha256:abcd",
+			want: want{
+				parsed: tf.Source{
+					URL:        "http://artifacts.acme.com:8080/modules/vpc.tar.gz",
+					Path:       "artifacts.acme.com/modules/vpc.tar.gz",
+					Host:       "artifacts.acme.com",
+					PathScheme: "http",
+					Subdir:     "/mod/vpc",
+					Query:      "checksum=sha256%3Aabcd",
+					Archive:    true,
+				},
+			},
+		},
+		{
+			name:   "https archive source with a checksum file URL",
+			source: "https://example.com/x.zip?checksum=file:https://example.com/SHA256SUMS",
+			want: want{
+				parsed: tf.Source{
+					URL:        "https://example.com/x.zip",
+					Path:       "example.com/x.zip",
+					Host:       "example.com",
+					PathScheme: "https",
+					Query:      "checksum=file%3Ahttps%3A%2F%2Fexample.com%2FSHA256SUMS",
+					Archive:    true,
+				},
+			},
+		},
+		{
+			name:   "https archive source with subdir and a checksum file URL",
+			source: "https://example.com/x.zip//mod?checksum=file:https://example.com/SHA256SUMS",
+			want: want{
+				parsed: tf.Source{
+					URL:        "https://example.com/x.zip",
+					Path:       "example.com/x.zip",
+					Host:       "example.com",
+					PathScheme: "https",
+					Subdir:     "/mod",
+					Query:      "checksum=file%3Ahttps%3A%2F%2Fexample.com%2FSHA256SUMS",
+					Archive:    true,
+				},
+			},
+		},
+		{
+			name:   "https archive source with empty subdir is invalid",
+			source: "https://example.com/vpc-module.zip//",
+			want: want{
+				err: errors.E(tf.ErrInvalidModSrc),
+			},
+		},
+		{
+			name:   "https github archive is not an archive source",
+			source: "https://github.com/terramate-io/example/archive/v1.zip",
 			want: want{
 				err: errors.E(tf.ErrUnsupportedModSrc),
 			},
 		},
 		{
-			name:   "https is not supported",
-			source: "https://example.com/vpc-module.zip",
+			name:   "https github archive with mixed-case host is not an archive source",
+			source: "https://GitHub.com/terramate-io/example/archive/v1.zip",
+			want: want{
+				err: errors.E(tf.ErrUnsupportedModSrc),
+			},
+		},
+		{
+			name:   "https source that is not an archive is not supported",
+			source: "https://example.com/vpc-module",
 			want: want{
 				err: errors.E(tf.ErrUnsupportedModSrc),
 			},
@@ -447,15 +1306,109 @@ func TestParseGitSources(t *testing.T) {
 			},
 		},
 		{
-			name:   "terraform registry is not supported",
+			name:   "local path with registry shape is local",
+			source: "./hashicorp/consul/aws",
+			want: want{
+				parsed: tf.Source{
+					Path:  "./hashicorp/consul/aws",
+					Local: true,
+				},
+			},
+		},
+		{
+			name:   "parent local path with registry shape is local",
+			source: "../hashicorp/consul/aws",
+			want: want{
+				parsed: tf.Source{
+					Path:  "../hashicorp/consul/aws",
+					Local: true,
+				},
+			},
+		},
+		{
+			name:   "terraform registry source",
 			source: "hashicorp/consul/aws",
 			want: want{
-				err: errors.E(tf.ErrUnsupportedModSrc),
+				parsed: tf.Source{
+					Path:      "registry.terraform.io/hashicorp/consul/aws",
+					Host:      "registry.terraform.io",
+					Registry:  true,
+					Namespace: "hashicorp",
+					Name:      "consul",
+					Provider:  "aws",
+				},
+			},
+		},
+		{
+			name:   "terraform registry source with subdir",
+			source: "hashicorp/consul/aws//modules/consul-cluster",
+			want: want{
+				parsed: tf.Source{
+					Path:      "registry.terraform.io/hashicorp/consul/aws",
+					Host:      "registry.terraform.io",
+					Subdir:    "/modules/consul-cluster",
+					Registry:  true,
+					Namespace: "hashicorp",
+					Name:      "consul",
+					Provider:  "aws",
+				},
+			},
+		},
+		{
+			name:   "terraform registry source with version",
+			source: "hashicorp/consul/aws?version=0.1.0",
+			want: want{
+				parsed: tf.Source{
+					Path:      "registry.terraform.io/hashicorp/consul/aws",
+					Host:      "registry.terraform.io",
+					Registry:  true,
+					Namespace: "hashicorp",
+					Name:      "consul",
+					Provider:  "aws",
+					Version:   "0.1.0",
+				},
 			},
 		},
 		{
-			name:   "registry is not supported",
+			name:   "private registry source",
 			source: "app.terraform.io/example-corp/k8s-cluster/azurerm",
+			want: want{
+				parsed: tf.Source{
+					Path:      "app.terraform.io/example-corp/k8s-cluster/azurerm",
+					Host:      "app.terraform.io",
+					Registry:  true,
+					Namespace: "example-corp",
+					Name:      "k8s-cluster",
+					Provider:  "azurerm",
+				},
+			},
+		},
+		{
+			name:   "private registry source with subdir and version",
+			source: "app.terraform.io/example-corp/k8s-cluster/azurerm//modules/aks?version=~>1.0",
+			want: want{
+				parsed: tf.Source{
+					Path:      "app.terraform.io/example-corp/k8s-cluster/azurerm",
+					Host:      "app.terraform.io",
+					Subdir:    "/modules/aks",
+					Registry:  true,
+					Namespace: "example-corp",
+					Name:      "k8s-cluster",
+					Provider:  "azurerm",
+					Version:   "~>1.0",
+				},
+			},
+		},
+		{
+			name:   "registry source with unknown query param is invalid",
+			source: "hashicorp/consul/aws?ref=v1",
+			want: want{
+				err: errors.E(tf.ErrInvalidModSrc),
+			},
+		},
+		{
+			name:   "registry source with invalid provider is not supported",
+			source: "hashicorp/consul/AWS",
 			want: want{
 				err: errors.E(tf.ErrUnsupportedModSrc),
 			},
@@ -482,6 +1435,22 @@ func TestParseGitSources(t *testing.T) {
 			t.Parallel()
 			got, err := tf.ParseSource(tcase.source)
 			assert.IsError(t, err, tcase.want.err)
+
+			// dst is reused, so it must be fully overwritten.
+			into := tf.Source{Raw: "dirty", Ref: "dirty", Local: true}
+			intoErr := tf.ParseSourceInto(&into, tcase.source)
+			assert.IsError(t, intoErr, tcase.want.err)
+			if into != got {
+				t.Errorf("ParseSourceInto() = %+v, want %+v", into, got)
+			}
+
+			// parsing again into the same dst gives the same result.
+			intoErr = tf.ParseSourceInto(&into, tcase.source)
+			assert.IsError(t, intoErr, tcase.want.err)
+			if into != got {
+				t.Errorf("ParseSourceInto() again = %+v, want %+v", into, got)
+			}
+
 			if tcase.want.err != nil {
 				return
 			}
@@ -490,3 +1459,540 @@ func TestParseGitSources(t *testing.T) {
 		})
 	}
 }
+
+func TestParseSourceStrict(t *testing.T) {
+	t.Parallel()
+
+	type testcase struct {
+		source      string
+		mutableRefs []string
+		err         error
+	}
+
+	for _, tc := range []testcase{
+		{
+			source: "github.com/terramate-io/example?ref=v1.0.0",
+		},
+		{
+			source: "github.com/terramate-io/example?ref=refs/tags/v1.0.0",
+		},
+		{
+			source: "git::https://example.com/vpc.git?ref=4e991b55e3d58b9c3137a791a9986ed9c5069697",
+		},
+		{
+			source: "github.com/terramate-io/example",
+			err:    errors.E(tf.ErrUnpinnedModSrc),
+		},
+		{
+			source: "git@github.com:terramate-io/example.git//sub?ref=main",
+			err:    errors.E(tf.ErrUnpinnedModSrc),
+		},
+		{
+			source: "github.com/terramate-io/example?ref=refs/heads/master",
+			err:    errors.E(tf.ErrUnpinnedModSrc),
+		},
+		{
+			source:      "github.com/terramate-io/example?ref=main",
+			mutableRefs: []string{"trunk"},
+		},
+		{
+			source:      "github.com/terramate-io/example?ref=trunk",
+			mutableRefs: []string{"trunk"},
+			err:         errors.E(tf.ErrUnpinnedModSrc),
+		},
+		{
+			source: "hashicorp/consul/aws?version=1.0.0",
+		},
+		{
+			source: "hashicorp/consul/aws",
+			err:    errors.E(tf.ErrUnpinnedModSrc),
+		},
+		{
+			source: "./modules/vpc",
+		},
+		{
+			source: "https://artifacts.acme.com/modules/vpc.zip",
+		},
+		{
+			source: "not a valid source",
+			err:    errors.E(tf.ErrUnsupportedModSrc),
+		},
+	} {
+		_, err := tf.ParseSourceStrict(tc.source, tc.mutableRefs...)
+		assert.IsError(t, err, tc.err, "parsing %q", tc.source)
+
+		// default parsing is not strict.
+		if errors.IsKind(tc.err, tf.ErrUnpinnedModSrc) {
+			_, err := tf.ParseSource(tc.source)
+			assert.NoError(t, err)
+		}
+	}
+}
+
+func TestParseSourceCached(t *testing.T) {
+	t.Parallel()
+
+	sources := []string{
+		"github.com/terramate-io/example//sub?ref=v1",
+		"git@github.com:terramate-io/example.git?ref=v2",
+		"hashicorp/consul/aws?version=1.0.0",
+		"./modules/vpc",
+		"not a valid source",
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		for _, source := range sources {
+			wg.Add(1)
+			go func(source string) {
+				defer wg.Done()
+
+				want, wantErr := tf.ParseSource(source)
+				got, err := tf.ParseSourceCached(source)
+				if wantErr != nil {
+					assert.IsError(t, err, wantErr)
+					return
+				}
+				assert.NoError(t, err)
+				if got != want {
+					t.Errorf("ParseSourceCached(%q) = %+v, want %+v", source, got, want)
+				}
+			}(source)
+		}
+	}
+	wg.Wait()
+
+	tf.ResetSourceCache()
+	got, err := tf.ParseSourceCached(sources[0])
+	assert.NoError(t, err)
+	assert.EqualStrings(t, sources[0], got.Raw)
+}
+
+func TestRegistrySourceDisambiguation(t *testing.T) {
+	t.Parallel()
+
+	type kind int
+	const (
+		unsupported kind = iota
+		local
+		registry
+	)
+
+	for source, want := range map[string]kind{
+		"./a/b/c":                    local,
+		"../a/b/c":                   local,
+		"/a/b/c":                     local,
+		"a/b/c":                      registry,
+		"a-b/c_d/aws":                registry,
+		"a/b/c//sub/dir":             registry,
+		"a/b/c?version=1.0.0":        registry,
+		"app.terraform.io/a/b/c":     registry,
+		"localhost:8080/a/b/c":       unsupported,
+		"a.b/c/d":                    unsupported,
+		"a/b.c/d":                    unsupported,
+		"a/b/c.d":                    unsupported,
+		"a/b/C":                      unsupported,
+		"a/b/c/d":                    unsupported,
+		"a/b/c/":                     unsupported,
+		"a//b/c":                     unsupported,
+		"a/b":                        unsupported,
+		"a":                          unsupported,
+		"registry.acme.io/a/b/c/d":   unsupported,
+		"registry.acme.io:443/a/b/c": registry,
+	} {
+		src, err := tf.ParseSource(source)
+		switch want {
+		case unsupported:
+			assert.IsError(t, err, errors.E(tf.ErrUnsupportedModSrc), "parsing %q", source)
+		case local:
+			assert.NoError(t, err, "parsing %q", source)
+			assert.IsTrue(t, src.Local, "%q is not a local source", source)
+		case registry:
+			assert.NoError(t, err, "parsing %q", source)
+			assert.IsTrue(t, src.Registry, "%q is not a registry source", source)
+		}
+	}
+}
+
+func TestParseSourceGitHubEnterprise(t *testing.T) {
+	t.Parallel()
+
+	opts := tf.ParseOptions{GitHubHosts: []string{"github.com", "github.acme.com"}}
+
+	got, err := tf.ParseSourceWithOptions("github.acme.com/platform/modules//vpc?ref=v1", opts)
+	assert.NoError(t, err)
+	want := tf.Source{
+		Raw:        "github.acme.com/platform/modules//vpc?ref=v1",
+		URL:        "https://github.acme.com/platform/modules.git",
+		Path:       "github.acme.com/platform/modules",
+		Host:       "github.acme.com",
+		PathScheme: "https",
+		Subdir:     "/vpc",
+		Ref:        "v1",
+	}
+	if got != want {
+		t.Fatalf("got %+v, want %+v", got, want)
+	}
+
+	// public GitHub sources have the same semantics.
+	public, err := tf.ParseSourceWithOptions("github.com/platform/modules//vpc?ref=v1", opts)
+	assert.NoError(t, err)
+	assert.EqualStrings(t, strings.Replace(want.URL, "github.acme.com", "github.com", 1), public.URL)
+	assert.EqualStrings(t, want.Subdir, public.Subdir)
+	assert.EqualStrings(t, want.Ref, public.Ref)
+
+	// the string form is parseable without the options.
+	assert.EqualStrings(t, "git::https://github.acme.com/platform/modules.git//vpc?ref=v1", got.String())
+	reparsed, err := tf.ParseSource(got.String())
+	assert.NoError(t, err)
+	assert.IsTrue(t, reparsed.Equal(got), "%q must be equal to %q", reparsed, got)
+
+	// the shorthand form is only written with the options.
+	short := got.StringWithOptions(opts)
+	assert.EqualStrings(t, "github.acme.com/platform/modules//vpc?ref=v1", short)
+	reparsed, err = tf.ParseSourceWithOptions(short, opts)
+	assert.NoError(t, err)
+	assert.IsTrue(t, reparsed.Equal(got), "%q must be equal to %q", reparsed, got)
+
+	// enterprise archive URLs are not archive sources, as for public GitHub.
+	const archive = "https://github.acme.com/platform/modules/archive/v1.zip"
+	_, err = tf.ParseSourceWithOptions(archive, opts)
+	assert.IsError(t, err, errors.E(tf.ErrUnsupportedModSrc))
+	plain, err := tf.ParseSource(archive)
+	assert.NoError(t, err)
+	assert.IsTrue(t, plain.Archive, "%q must be an archive without the options", archive)
+
+	_, err = tf.ParseSource("github.acme.com/platform/modules//vpc?ref=v1")
+	assert.IsError(t, err, errors.E(tf.ErrUnsupportedModSrc))
+
+	// hosts must match a whole path segment.
+	_, err = tf.ParseSourceWithOptions("github.acme.company/platform/modules?ref=v1", opts)
+	assert.IsError(t, err, errors.E(tf.ErrUnsupportedModSrc))
+}
+
+func TestParseRegistrySourceSubdir(t *testing.T) {
+	t.Parallel()
+
+	type testcase struct {
+		source string
+		want   tf.Source
+	}
+
+	for _, tc := range []testcase{
+		{
+			source: "hashicorp/consul/aws//modules/consul-cluster?version=0.1.0",
+			want: tf.Source{
+				Path:      "registry.terraform.io/hashicorp/consul/aws",
+				Host:      "registry.terraform.io",
+				Subdir:    "/modules/consul-cluster",
+				Registry:  true,
+				Namespace: "hashicorp",
+				Name:      "consul",
+				Provider:  "aws",
+				Version:   "0.1.0",
+			},
+		},
+		{
+			source: "hashicorp/consul/aws?version=0.1.0//modules/consul-cluster",
+			want: tf.Source{
+				Path:      "registry.terraform.io/hashicorp/consul/aws",
+				Host:      "registry.terraform.io",
+				Subdir:    "/modules/consul-cluster",
+				Registry:  true,
+				Namespace: "hashicorp",
+				Name:      "consul",
+				Provider:  "aws",
+				Version:   "0.1.0",
+			},
+		},
+		{
+			source: "app.terraform.io/example-corp/k8s-cluster/azurerm//modules/aks/node-pool?version=%3E%3D+1.0%2C+%3C+2.0",
+			want: tf.Source{
+				Path:      "app.terraform.io/example-corp/k8s-cluster/azurerm",
+				Host:      "app.terraform.io",
+				Subdir:    "/modules/aks/node-pool",
+				Registry:  true,
+				Namespace: "example-corp",
+				Name:      "k8s-cluster",
+				Provider:  "azurerm",
+				Version:   ">= 1.0, < 2.0",
+			},
+		},
+	} {
+		got, err := tf.ParseSource(tc.source)
+		assert.NoError(t, err, "parsing %q", tc.source)
+		tc.want.Raw = tc.source
+		if got != tc.want {
+			t.Fatalf("parsing %q: got %#v, want %#v", tc.source, got, tc.want)
+		}
+
+		reparsed, err := tf.ParseSource(got.String())
+		assert.NoError(t, err, "parsing %q", got.String())
+		assert.IsTrue(t, reparsed.Equal(got), "%q must round-trip", tc.source)
+	}
+
+	for _, source := range []string{
+		"hashicorp/consul/aws//",
+		"hashicorp/consul/aws//a//b",
+		"hashicorp/consul/aws//modules/x?version=1.0.0&depth=1",
+	} {
+		_, err := tf.ParseSource(source)
+		assert.IsError(t, err, errors.E(tf.ErrInvalidModSrc), "parsing %q", source)
+	}
+}
+
+func TestParseGitSourceSubdirQueryOrdering(t *testing.T) {
+	t.Parallel()
+
+	for _, tc := range []struct {
+		pathFirst  string
+		queryFirst string
+	}{
+		{
+			pathFirst:  "git::https://example.com/repo.git//subdir?ref=v1",
+			queryFirst: "git::https://example.com/repo.git?ref=v1//subdir",
+		},
+		{
+			pathFirst:  "git::ssh://git@example.com:2222/repo.git//a/b?depth=1&ref=v1",
+			queryFirst: "git::ssh://git@example.com:2222/repo.git?ref=v1//a/b&depth=1",
+		},
+		{
+			pathFirst:  "git::file:///opt/mirror/repo.git//subdir?ref=v1",
+			queryFirst: "git::file:///opt/mirror/repo.git?ref=v1//subdir",
+		},
+	} {
+		want := test.ParseSource(t, tc.pathFirst)
+		got := test.ParseSource(t, tc.queryFirst)
+		assert.EqualStrings(t, want.Subdir, got.Subdir, "subdir of %q", tc.queryFirst)
+		assert.EqualStrings(t, want.Ref, got.Ref, "ref of %q", tc.queryFirst)
+		assert.EqualStrings(t, want.Query, got.Query, "query of %q", tc.queryFirst)
+		assert.IsTrue(t, got.Equal(want), "%q must be equal to %q", tc.queryFirst, tc.pathFirst)
+		assert.EqualStrings(t, tc.pathFirst, got.String())
+	}
+}
+
+func TestParseSourceDefaultRef(t *testing.T) {
+	t.Parallel()
+
+	opts := tf.ParseOptions{DefaultRef: "main"}
+
+	type testcase struct {
+		source string
+		ref    string
+		str    string
+	}
+
+	for _, tc := range []testcase{
+		{
+			source: "github.com/terramate-io/example//vpc",
+			ref:    "main",
+			str:    "github.com/terramate-io/example//vpc?ref=main",
+		},
+		{
+			source: "git::https://example.com/vpc.git?depth=1",
+			ref:    "main",
+			str:    "git::https://example.com/vpc.git?depth=1&ref=main",
+		},
+		{
+			source: "git@github.com:terramate-io/example.git",
+			ref:    "main",
+			str:    "git@github.com:terramate-io/example.git?ref=main",
+		},
+		{
+			source: "github.com/terramate-io/example?ref=v1",
+			ref:    "v1",
+			str:    "github.com/terramate-io/example?ref=v1",
+		},
+		{
+			source: "../modules/vpc",
+			str:    "../modules/vpc",
+		},
+		{
+			source: "hashicorp/consul/aws",
+			str:    "hashicorp/consul/aws",
+		},
+		{
+			source: "https://example.com/vpc.zip",
+			str:    "https://example.com/vpc.zip",
+		},
+	} {
+		got, err := tf.ParseSourceWithOptions(tc.source, opts)
+		assert.NoError(t, err, "parsing %q", tc.source)
+		assert.EqualStrings(t, tc.ref, got.Ref, "ref of %q", tc.source)
+		assert.EqualStrings(t, tc.source, got.Raw, "raw of %q", tc.source)
+		assert.EqualStrings(t, tc.str, got.String(), "string of %q", tc.source)
+	}
+
+	_, err := tf.ParseSourceWithOptions("github.com/terramate-io/example",
+		tf.ParseOptions{DefaultRef: "invalid ref"})
+	assert.IsError(t, err, errors.E(tf.ErrInvalidModSrc))
+}
+
+func TestParseRegistrySourceVersion(t *testing.T) {
+	t.Parallel()
+
+	type testcase struct {
+		source  string
+		version string
+		subdir  string
+		err     error
+	}
+
+	for _, tc := range []testcase{
+		{
+			source: "app.terraform.io/corp/mod/aws",
+		},
+		{
+			source:  "app.terraform.io/corp/mod/aws@1.2.0",
+			version: "1.2.0",
+		},
+		{
+			source:  "app.terraform.io/corp/mod/aws?version=~>1.2",
+			version: "~>1.2",
+		},
+		{
+			source:  "app.terraform.io/corp/mod/aws@1.2.0//modules/x",
+			version: "1.2.0",
+			subdir:  "/modules/x",
+		},
+		{
+			source:  "corp/mod/aws@v1.2.0",
+			version: "v1.2.0",
+		},
+		{
+			source: "app.terraform.io/corp/mod/aws@",
+			err:    errors.E(tf.ErrInvalidModSrc),
+		},
+		{
+			source: "app.terraform.io/corp/mod/aws@1.2.0?version=1.2.0",
+			err:    errors.E(tf.ErrInvalidModSrc),
+		},
+		{
+			source: "app.terraform.io/corp@x/mod/aws",
+			err:    errors.E(tf.ErrUnsupportedModSrc),
+		},
+	} {
+		got, err := tf.ParseSource(tc.source)
+		assert.IsError(t, err, tc.err, "parsing %q", tc.source)
+		if tc.err != nil {
+			continue
+		}
+		assert.IsTrue(t, got.Registry, "%q is not a registry source", tc.source)
+		assert.EqualStrings(t, "app.terraform.io/corp/mod/aws",
+			strings.Replace(got.Path, tf.DefaultRegistryHost, "app.terraform.io", 1))
+		assert.EqualStrings(t, tc.version, got.Version, "version of %q", tc.source)
+		assert.EqualStrings(t, tc.subdir, got.Subdir, "subdir of %q", tc.source)
+	}
+}
+
+func TestParseSourceGitHubTreeURL(t *testing.T) {
+	t.Parallel()
+
+	for _, tc := range []struct {
+		source string
+		want   string
+	}{
+		{
+			source: "github.com/org/repo/tree/v1.2.0/modules/vpc",
+			want:   "github.com/org/repo//modules/vpc?ref=v1.2.0",
+		},
+		{
+			source: "github.com/org/repo/tree/main/modules/vpc/",
+			want:   "github.com/org/repo//modules/vpc?ref=main",
+		},
+		{
+			source: "https://github.com/org/repo/tree/develop",
+			want:   "github.com/org/repo?ref=develop",
+		},
+		{
+			source: "http://github.com/org/repo/tree/v2/a//b",
+			want:   "github.com/org/repo//a/b?ref=v2",
+		},
+	} {
+		suggestion, ok := tf.SuggestSource(tc.source)
+		assert.IsTrue(t, ok, "SuggestSource(%q) found no suggestion", tc.source)
+		assert.EqualStrings(t, tc.want, suggestion)
+
+		// the suggestion is a valid source.
+		_ = test.ParseSource(t, suggestion)
+
+		_, err := tf.ParseSource(tc.source)
+		assert.IsError(t, err, errors.E(tf.ErrInvalidModSrc))
+		if !strings.Contains(err.Error(), strconv.Quote(tc.want)) {
+			t.Errorf("ParseSource(%q) error %q doesn't suggest %q", tc.source, err, tc.want)
+		}
+	}
+
+	for _, source := range []string{
+		"github.com/org/repo//modules/vpc?ref=v1",
+		"github.com/org/repo/tree",
+		"github.com/org/tree/v1/mod",
+		"gitlab.com/org/repo/tree/v1/mod",
+		"github.com/org/repo/tree/v1/mod?ref=v2",
+	} {
+		_, ok := tf.SuggestSource(source)
+		assert.IsTrue(t, !ok, "SuggestSource(%q) found a suggestion", source)
+	}
+
+	// GitHub Enterprise hosts configured in the options are detected.
+	_, err := tf.ParseSourceWithOptions("github.acme.com/org/repo/tree/v1/mod", tf.ParseOptions{
+		GitHubHosts: []string{"github.acme.com"},
+	})
+	assert.IsError(t, err, errors.E(tf.ErrInvalidModSrc))
+}
+
+func TestParseSourceNoisyPaths(t *testing.T) {
+	t.Parallel()
+
+	for _, tc := range []struct {
+		noisy     string
+		canonical string
+	}{
+		{noisy: "github.com/org/repo/", canonical: "github.com/org/repo"},
+		{noisy: "github.com/org/repo.git/", canonical: "github.com/org/repo"},
+		{noisy: "github.com/org/repo//sub/?ref=v1", canonical: "github.com/org/repo//sub?ref=v1"},
+		{noisy: "github.com/org/repo?ref=v1//sub/", canonical: "github.com/org/repo//sub?ref=v1"},
+		{noisy: "bitbucket.org/org/repo/?ref=v1", canonical: "bitbucket.org/org/repo?ref=v1"},
+		{noisy: "gitlab.com/group/sub/repo/", canonical: "gitlab.com/group/sub/repo"},
+		{noisy: "git@github.com:org/repo.git/", canonical: "git@github.com:org/repo.git"},
+		{noisy: "git@github.com:org/repo.git//mod/", canonical: "git@github.com:org/repo.git//mod"},
+		{noisy: "git::https://example.com/org/repo.git/?ref=v1", canonical: "git::https://example.com/org/repo.git?ref=v1"},
+		{noisy: "github.com/org//repo", canonical: "github.com/org/repo"},
+		{noisy: "github.com//org///repo//mod?ref=v1", canonical: "github.com/org/repo//mod?ref=v1"},
+		{noisy: "bitbucket.org/org//repo/", canonical: "bitbucket.org/org/repo"},
+		{noisy: "github.com/org/repo//./a/./b", canonical: "github.com/org/repo//a/b"},
+		{noisy: "github.com/org/repo//a/./?ref=v1", canonical: "github.com/org/repo//a?ref=v1"},
+		{noisy: "git::https://example.com/org/repo.git//./mod/", canonical: "git::https://example.com/org/repo.git//mod"},
+		{noisy: "ssh://git@example.com/org/repo.git/", canonical: "ssh://git@example.com/org/repo.git"},
+		{noisy: "https://example.com/org/repo.git/", canonical: "https://example.com/org/repo.git"},
+		{noisy: "https://example.com/vpc.zip//mod/", canonical: "https://example.com/vpc.zip//mod"},
+		{noisy: "hashicorp/consul/aws//modules/consul-cluster/", canonical: "hashicorp/consul/aws//modules/consul-cluster"},
+	} {
+		noisy := test.ParseSource(t, tc.noisy)
+		canonical := test.ParseSource(t, tc.canonical)
+		if !noisy.Equal(canonical) {
+			t.Errorf("ParseSource(%q) = %+v, want equal to %+v", tc.noisy, noisy, canonical)
+		}
+		assert.EqualStrings(t, canonical.Path, noisy.Path, "Path of %q", tc.noisy)
+		assert.EqualStrings(t, canonical.Subdir, noisy.Subdir, "Subdir of %q", tc.noisy)
+	}
+}
+
+func TestParseSourceNoisyPathsInvalid(t *testing.T) {
+	t.Parallel()
+
+	for _, modsource := range []string{
+		// a trailing "//" is a second subdir separator.
+		"git::https://example.com/org/repo.git//mod//",
+		"github.com/org/repo//mod//",
+		// a subdir with only "./" segments is empty.
+		"github.com/org/repo//.",
+		"github.com/org/repo//./",
+		// Gitlab package paths have any number of segments, so the "//"
+		// is always a subdir separator.
+		"gitlab.com/group//sub//repo",
+	} {
+		_, err := tf.ParseSource(modsource)
+		assert.IsError(t, err, errors.E(tf.ErrInvalidModSrc), "parsing %q", modsource)
+	}
+}
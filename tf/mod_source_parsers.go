@@ -0,0 +1,93 @@
+// Copyright 2024 Terramate GmbH
+// SPDX-License-Identifier: MPL-2.0
+
+package tf
+
+import (
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/terramate-io/terramate/errors"
+)
+
+type sourceParser struct {
+	prefix string
+	parse  func(string) (Source, error)
+}
+
+var sourceParsers struct {
+	sync.RWMutex
+	// parsers are sorted by descending prefix length.
+	parsers []sourceParser
+}
+
+// RegisterSourceParser teaches [ParseSource] to parse module sources starting
+// with prefix using fn, eg.: acme:// for acme://team/module@v1
+//
+// The built-in sources are always checked first, so registered parsers can
+// only handle sources that would fail with [ErrUnsupportedModSrc]. If many
+// registered prefixes match a source then the parser of the longest prefix is
+// used. Registering a prefix again replaces its previous parser.
+//
+// The [Source.Raw] field is set by [ParseSource], which also checks the
+// parsed source with [Source.Validate]. A subdir written after the query is
+// moved back to the end of the path before fn is called, as done for the
+// built-in sources.
+//
+// A panic of fn is recovered and returned as an error of kind
+// [ErrInvalidModSrc], so a faulty parser can't abort the parsing of all the
+// sources, eg.: by [ValidateSources].
+//
+// It's safe for concurrent use, but registering parsers after module sources
+// were parsed with [ParseSourceCached] doesn't change the cached results.
+// It panics if prefix is empty or fn is nil.
+func RegisterSourceParser(prefix string, fn func(string) (Source, error)) {
+	if prefix == "" {
+		panic("tf: RegisterSourceParser with an empty prefix")
+	}
+	if fn == nil {
+		panic("tf: RegisterSourceParser with a nil parser for " + prefix)
+	}
+
+	sourceParsers.Lock()
+	defer sourceParsers.Unlock()
+
+	parsers := sourceParsers.parsers[:0:0]
+	for _, p := range sourceParsers.parsers {
+		if p.prefix != prefix {
+			parsers = append(parsers, p)
+		}
+	}
+	parsers = append(parsers, sourceParser{prefix: prefix, parse: fn})
+	sort.SliceStable(parsers, func(i, j int) bool {
+		return len(parsers[i].prefix) > len(parsers[j].prefix)
+	})
+	sourceParsers.parsers = parsers
+}
+
+// lookupSourceParser returns the registered parser for modsource, if any.
+func lookupSourceParser(modsource string) (func(string) (Source, error), bool) {
+	sourceParsers.RLock()
+	defer sourceParsers.RUnlock()
+
+	for _, p := range sourceParsers.parsers {
+		if strings.HasPrefix(modsource, p.prefix) {
+			return p.parse, true
+		}
+	}
+	return nil, false
+}
+
+// callSourceParser calls the registered parse function for modsource,
+// returning its panic, if any, as an error.
+func callSourceParser(parse func(string) (Source, error), modsource string) (src Source, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			src = Source{}
+			err = errors.E(ErrInvalidModSrc,
+				"registered parser panicked parsing source %q: %v", modsource, r)
+		}
+	}()
+	return parse(modsource)
+}
@@ -0,0 +1,94 @@
+// Copyright 2024 Terramate GmbH
+// SPDX-License-Identifier: MPL-2.0
+
+package tf_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/madlambda/spells/assert"
+	"github.com/terramate-io/terramate/errors"
+	"github.com/terramate-io/terramate/test"
+	"github.com/terramate-io/terramate/tf"
+)
+
+func TestRegisterSourceParser(t *testing.T) {
+	t.Parallel()
+
+	parseAcme := func(modsource string) (tf.Source, error) {
+		addr := strings.TrimPrefix(modsource, "acme://")
+		module, version, _ := strings.Cut(addr, "@")
+		if module == "" {
+			return tf.Source{}, errors.E(tf.ErrInvalidModSrc, "acme source %q has no module", modsource)
+		}
+		return tf.Source{
+			URL:        "https://git.acme.com/" + module + ".git",
+			Path:       "git.acme.com/" + module,
+			Host:       "git.acme.com",
+			PathScheme: "https",
+			Ref:        version,
+		}, nil
+	}
+	tf.RegisterSourceParser("acme://", parseAcme)
+	tf.RegisterSourceParser("acme://legacy/", func(string) (tf.Source, error) {
+		return tf.Source{}, errors.E(tf.ErrUnsupportedModSrc, "legacy acme modules are not supported")
+	})
+
+	got, err := tf.ParseSource("acme://team/module@v1")
+	assert.NoError(t, err)
+	test.AssertDiff(t, got, tf.Source{
+		Raw:        "acme://team/module@v1",
+		URL:        "https://git.acme.com/team/module.git",
+		Path:       "git.acme.com/team/module",
+		Host:       "git.acme.com",
+		PathScheme: "https",
+		Ref:        "v1",
+	})
+
+	// the longest prefix wins.
+	_, err = tf.ParseSource("acme://legacy/module@v1")
+	assert.IsError(t, err, errors.E(tf.ErrUnsupportedModSrc))
+
+	// errors of the registered parser are returned.
+	_, err = tf.ParseSource("acme://")
+	assert.IsError(t, err, errors.E(tf.ErrInvalidModSrc))
+
+	// the parsed source is validated.
+	tf.RegisterSourceParser("nopath://", func(string) (tf.Source, error) {
+		return tf.Source{URL: "https://example.com/repo.git"}, nil
+	})
+	_, err = tf.ParseSource("nopath://module")
+	assert.IsError(t, err, errors.E(tf.ErrInvalidModSrc))
+
+	// built-in sources are never handled by registered parsers.
+	tf.RegisterSourceParser("github.com/", func(string) (tf.Source, error) {
+		t.Fatal("registered parser called for a built-in source")
+		return tf.Source{}, nil
+	})
+	got, err = tf.ParseSource("github.com/terramate-io/example?ref=v1")
+	assert.NoError(t, err)
+	assert.EqualStrings(t, "github.com/terramate-io/example", got.Path)
+
+	// unknown schemes are still unsupported.
+	_, err = tf.ParseSource("other://team/module")
+	assert.IsError(t, err, errors.E(tf.ErrUnsupportedModSrc))
+
+	// panics of registered parsers are errors, so they are reported by
+	// ValidateSources with the other sources.
+	tf.RegisterSourceParser("panic://", func(string) (tf.Source, error) {
+		panic("faulty parser")
+	})
+	_, err = tf.ParseSource("panic://module")
+	assert.IsError(t, err, errors.E(tf.ErrInvalidModSrc))
+
+	diags := tf.ValidateSources(map[string]string{
+		"a.tf:1": "panic://module",
+		"b.tf:1": "github.com/terramate-io/example?ref=v1",
+	})
+	assert.EqualInts(t, 1, len(diags))
+	assert.EqualStrings(t, "a.tf:1", diags[0].Label)
+	assert.IsTrue(t, diags[0].Severity == tf.SeverityError)
+	assert.IsTrue(t, strings.Contains(diags[0].Message, "faulty parser"),
+		"message %q must have the panic value", diags[0].Message)
+}
@@ -0,0 +1,1115 @@
+// Copyright 2024 Terramate GmbH
+// SPDX-License-Identifier: MPL-2.0
+
+package tf_test
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/madlambda/spells/assert"
+	"github.com/terramate-io/terramate/errors"
+	"github.com/terramate-io/terramate/test"
+	"github.com/terramate-io/terramate/tf"
+)
+
+func TestSourceString(t *testing.T) {
+	t.Parallel()
+
+	type testcase struct {
+		source string
+		want   string
+		remote bool
+	}
+
+	for _, tc := range []testcase{
+		{
+			source: "github.com/terramate-io/example",
+			want:   "github.com/terramate-io/example",
+			remote: true,
+		},
+		{
+			source: "github.com/terramate-io/example.git//subdir?ref=v1",
+			want:   "github.com/terramate-io/example//subdir?ref=v1",
+			remote: true,
+		},
+		{
+			source: "GitHub.com/Org/Repo//vpc?ref=v1",
+			want:   "GitHub.com/Org/Repo//vpc?ref=v1",
+			remote: true,
+		},
+		{
+			source: "bitbucket.org/hashicorp/terraform-consul-aws?ref=v1",
+			want:   "bitbucket.org/hashicorp/terraform-consul-aws?ref=v1",
+			remote: true,
+		},
+		{
+			source: "gitlab.com/group/subgroup/repo.git//vpc?ref=v1",
+			want:   "gitlab.com/group/subgroup/repo//vpc?ref=v1",
+			remote: true,
+		},
+		{
+			source: "git@github.com:terramate-io/example.git//sub/dir?ref=v2",
+			want:   "git@github.com:terramate-io/example.git//sub/dir?ref=v2",
+			remote: true,
+		},
+		{
+			source: "gitea@git.internal.acme:platform/modules.git//vpc?ref=v2",
+			want:   "gitea@git.internal.acme:platform/modules.git//vpc?ref=v2",
+			remote: true,
+		},
+		{
+			source: "git::https://example.com/vpc.git//sub/dir?ref=v3",
+			want:   "git::https://example.com/vpc.git//sub/dir?ref=v3",
+			remote: true,
+		},
+		{
+			source: "git::https://example.com/infra.git//modules/net?ref=main&depth=1",
+			want:   "git::https://example.com/infra.git//modules/net?depth=1&ref=main",
+			remote: true,
+		},
+		{
+			source: "github.com/terramate-io/example?key=v1",
+			want:   "github.com/terramate-io/example?key=v1",
+			remote: true,
+		},
+		{
+			source: "git::https://example.com:443/vpc.git?ref=v3",
+			want:   "git::https://example.com:443/vpc.git?ref=v3",
+			remote: true,
+		},
+		{
+			source: "git::ssh://username@example.com/storage.git//subdir",
+			want:   "git::ssh://username@example.com/storage.git//subdir",
+			remote: true,
+		},
+		{
+			source: "git::file:///tmp/test/repo//subdir",
+			want:   "git::file:///tmp/test/repo//subdir",
+			remote: true,
+		},
+		{
+			source: "https://artifacts.acme.com/modules/vpc-1.2.0.zip//vpc?checksum=md5:1234",
+			want:   "https://artifacts.acme.com/modules/vpc-1.2.0.zip//vpc?checksum=md5%3A1234",
+			remote: true,
+		},
+		{
+			source: "hashicorp/consul/aws//modules/consul-cluster?version=1.0.0",
+			want:   "hashicorp/consul/aws//modules/consul-cluster?version=1.0.0",
+			remote: true,
+		},
+		{
+			source: "app.terraform.io/example-corp/k8s-cluster/azurerm",
+			want:   "app.terraform.io/example-corp/k8s-cluster/azurerm",
+			remote: true,
+		},
+		{
+			source: "git::file:///opt/mirror/modules.git//vpc?ref=v1",
+			want:   "git::file:///opt/mirror/modules.git//vpc?ref=v1",
+			remote: true,
+		},
+		{
+			source: "ssh://git@example.com:2222/org/vpc.git//mod?ref=v1",
+			want:   "git::ssh://git@example.com:2222/org/vpc.git//mod?ref=v1",
+			remote: true,
+		},
+		{
+			source: "./modules//vpc/",
+			want:   "./modules/vpc",
+			remote: false,
+		},
+		{
+			source: "../modules/vpc",
+			want:   "../modules/vpc",
+			remote: false,
+		},
+		{
+			source: "/modules/vpc",
+			want:   "/modules/vpc",
+			remote: false,
+		},
+		{
+			source: "./",
+			want:   "./",
+			remote: false,
+		},
+		{
+			source: "./a/../",
+			want:   "./",
+			remote: false,
+		},
+		{
+			source: "../a/..",
+			want:   "../",
+			remote: false,
+		},
+	} {
+		tc := tc
+		t.Run(tc.source, func(t *testing.T) {
+			t.Parallel()
+
+			parsed := test.ParseSource(t, tc.source)
+			got := parsed.String()
+			assert.EqualStrings(t, tc.want, got)
+			assert.IsTrue(t, parsed.IsRemote() == tc.remote,
+				"IsRemote() = %t, want %t", parsed.IsRemote(), tc.remote)
+
+			reparsed := test.ParseSource(t, got)
+			reparsed.Raw = parsed.Raw
+			test.AssertDiff(t, reparsed, parsed)
+		})
+	}
+
+	assert.EqualStrings(t, "", tf.Source{}.String())
+}
+
+func TestNormalizeRef(t *testing.T) {
+	t.Parallel()
+
+	for ref, want := range map[string]string{
+		"":                   "",
+		"v1.0.0":             "v1.0.0",
+		"main":               "main",
+		"refs/tags/v1.0.0":   "v1.0.0",
+		"refs/heads/main":    "main",
+		"refs/remotes/main":  "refs/remotes/main",
+		"refs/heads/feat/x":  "feat/x",
+		"refs/tags/refs/x/y": "refs/x/y",
+	} {
+		assert.EqualStrings(t, want, tf.NormalizeRef(ref), "normalizing %q", ref)
+	}
+}
+
+func TestSourceRefType(t *testing.T) {
+	t.Parallel()
+
+	type testcase struct {
+		source string
+		want   tf.RefType
+	}
+
+	for _, tc := range []testcase{
+		{
+			source: "github.com/terramate-io/example",
+			want:   tf.RefNone,
+		},
+		{
+			source: "github.com/terramate-io/example?ref=v1.2.3",
+			want:   tf.RefSemver,
+		},
+		{
+			source: "github.com/terramate-io/example?ref=1.2.3",
+			want:   tf.RefSemver,
+		},
+		{
+			source: "github.com/terramate-io/example?ref=refs/tags/v1.2.3-rc.1",
+			want:   tf.RefSemver,
+		},
+		{
+			source: "github.com/terramate-io/example?ref=v1.2",
+			want:   tf.RefBranch,
+		},
+		{
+			source: "github.com/terramate-io/example?ref=0a1b2c3d4e5f60718293a4b5c6d7e8f901234567",
+			want:   tf.RefSHA,
+		},
+		{
+			source: "github.com/terramate-io/example?ref=" + strings.Repeat("ab", 32),
+			want:   tf.RefSHA,
+		},
+		{
+			source: "github.com/terramate-io/example?ref=0a1b2c3",
+			want:   tf.RefBranch,
+		},
+		{
+			source: "github.com/terramate-io/example?ref=main",
+			want:   tf.RefBranch,
+		},
+		{
+			source: "github.com/terramate-io/example?ref=refs/heads/feature/x",
+			want:   tf.RefBranch,
+		},
+	} {
+		src := test.ParseSource(t, tc.source)
+		assert.EqualInts(t, int(tc.want), int(src.RefType()), "RefType() of %q", tc.source)
+	}
+}
+
+func TestSourceSameModule(t *testing.T) {
+	t.Parallel()
+
+	type testcase struct {
+		a, b string
+		want bool
+	}
+
+	for _, tc := range []testcase{
+		{
+			a:    "github.com/terramate-io/example?ref=v1.0.0",
+			b:    "github.com/terramate-io/example?ref=refs/tags/v1.0.0",
+			want: true,
+		},
+		{
+			a:    "github.com/terramate-io/example?ref=main",
+			b:    "git::https://github.com/terramate-io/example.git?ref=refs/heads/main",
+			want: true,
+		},
+		{
+			a:    "github.com/terramate-io/example",
+			b:    "github.com/terramate-io/example.git",
+			want: true,
+		},
+		{
+			a:    "github.com/terramate-io/example",
+			b:    "github.com/terramate-io/example?ref=main",
+			want: false,
+		},
+		{
+			a:    "github.com/terramate-io/example?ref=v1",
+			b:    "github.com/terramate-io/example?ref=v2",
+			want: false,
+		},
+		{
+			a:    "github.com/terramate-io/example?ref=v1",
+			b:    "github.com/terramate-io/other?ref=v1",
+			want: false,
+		},
+	} {
+		a := test.ParseSource(t, tc.a)
+		b := test.ParseSource(t, tc.b)
+		if got := a.SameModule(b); got != tc.want {
+			t.Errorf("%q.SameModule(%q) = %t, want %t", tc.a, tc.b, got, tc.want)
+		}
+		if got := b.SameModule(a); got != tc.want {
+			t.Errorf("%q.SameModule(%q) = %t, want %t", tc.b, tc.a, got, tc.want)
+		}
+	}
+}
+
+func TestSourceSamePackage(t *testing.T) {
+	t.Parallel()
+
+	type testcase struct {
+		a, b      string
+		want      bool
+		wantEqual bool
+	}
+
+	for _, tc := range []testcase{
+		{
+			a:         "github.com/terramate-io/example//vpc?ref=v1",
+			b:         "github.com/terramate-io/example//vpc?ref=v2",
+			want:      true,
+			wantEqual: false,
+		},
+		{
+			a:         "github.com/terramate-io/example//vpc?ref=v1",
+			b:         "git::https://github.com/terramate-io/example.git//vpc",
+			want:      true,
+			wantEqual: false,
+		},
+		{
+			a:         "github.com/terramate-io/example//vpc?ref=v1",
+			b:         "github.com/terramate-io/example//vpc?ref=v1",
+			want:      true,
+			wantEqual: true,
+		},
+		{
+			a:         "git@github.com:terramate-io/example.git?ref=v1",
+			b:         "github.com/terramate-io/example?ref=v2",
+			want:      true,
+			wantEqual: false,
+		},
+		{
+			a:    "github.com/terramate-io/example//vpc?ref=v1",
+			b:    "github.com/terramate-io/example//other?ref=v1",
+			want: false,
+		},
+		{
+			a:    "github.com/terramate-io/example?ref=v1",
+			b:    "github.com/terramate-io/other?ref=v1",
+			want: false,
+		},
+		{
+			a:         "git::https://GitHub.com/terramate-io/example.git//vpc?ref=v1",
+			b:         "github.com/terramate-io/example//vpc?ref=v2",
+			want:      true,
+			wantEqual: false,
+		},
+		{
+			a:         "GitHub.com/Org/Repo//vpc?ref=v1",
+			b:         "github.com/Org/Repo//vpc?ref=v1",
+			want:      true,
+			wantEqual: true,
+		},
+		{
+			a:         "BITBUCKET.ORG/org/repo?ref=v1",
+			b:         "bitbucket.org/org/repo?ref=v2",
+			want:      true,
+			wantEqual: false,
+		},
+		{
+			a:    "GitHub.com/Org/Repo//vpc?ref=v1",
+			b:    "github.com/org/repo//vpc?ref=v1",
+			want: false,
+		},
+		{
+			a:    "git::https://github.com/Terramate-IO/Example.git//vpc?ref=v1",
+			b:    "github.com/terramate-io/example//vpc?ref=v1",
+			want: false,
+		},
+		{
+			a:    "github.com/terramate-io/example//VPC?ref=v1",
+			b:    "github.com/terramate-io/example//vpc?ref=v1",
+			want: false,
+		},
+	} {
+		a := test.ParseSource(t, tc.a)
+		b := test.ParseSource(t, tc.b)
+		if got := a.SamePackage(b); got != tc.want {
+			t.Errorf("%q.SamePackage(%q) = %t, want %t", tc.a, tc.b, got, tc.want)
+		}
+		if got := b.SamePackage(a); got != tc.want {
+			t.Errorf("%q.SamePackage(%q) = %t, want %t", tc.b, tc.a, got, tc.want)
+		}
+		if got := a.Equal(b); got != tc.wantEqual {
+			t.Errorf("%q.Equal(%q) = %t, want %t", tc.a, tc.b, got, tc.wantEqual)
+		}
+	}
+}
+
+func TestSourceEqual(t *testing.T) {
+	t.Parallel()
+
+	type testcase struct {
+		a, b string
+		want bool
+	}
+
+	for _, tc := range []testcase{
+		{
+			a:    "github.com/terramate-io/example",
+			b:    "github.com/terramate-io/example.git",
+			want: true,
+		},
+		{
+			a:    "github.com/terramate-io/example//sub?ref=v1",
+			b:    "git::https://github.com/terramate-io/example.git//sub?ref=v1",
+			want: true,
+		},
+		{
+			a:    "git::https://example.com/vpc?ref=v1&depth=1",
+			b:    "git::https://example.com/vpc.git?depth=1&ref=refs/tags/v1",
+			want: true,
+		},
+		{
+			a:    "git@github.com:terramate-io/example.git?ref=v1",
+			b:    "git@github.com:terramate-io/example.git?ref=v1",
+			want: true,
+		},
+		{
+			a:    "git@github.com:terramate-io/example.git//mod",
+			b:    "git@github.com:terramate-io/example//mod",
+			want: true,
+		},
+		{
+			a:    "git@github.com:terramate-io/example.git//mod?ref=v1",
+			b:    "git@github.com:terramate-io/example//mod?ref=refs/tags/v1",
+			want: true,
+		},
+		{
+			a:    "git@github.com:terramate-io/example.git",
+			b:    "github.com/terramate-io/example",
+			want: false,
+		},
+		{
+			a:    "github.com/terramate-io/example//sub",
+			b:    "github.com/terramate-io/example//other",
+			want: false,
+		},
+		{
+			a:    "github.com/terramate-io/example?ref=v1",
+			b:    "github.com/terramate-io/example?ref=v2",
+			want: false,
+		},
+		{
+			a:    "git::https://example.com/vpc?depth=1",
+			b:    "git::https://example.com/vpc",
+			want: false,
+		},
+		{
+			a:    "git::https://GitHub.com/Org/Repo.git?ref=v1",
+			b:    "github.com/Org/Repo?ref=v1",
+			want: true,
+		},
+		{
+			a:    "git@GITHUB.COM:terramate-io/example.git",
+			b:    "git@github.com:terramate-io/example.git",
+			want: true,
+		},
+		{
+			a:    "git::https://Example.com/Team/VPC.git",
+			b:    "git::https://example.com/team/vpc.git",
+			want: false,
+		},
+		{
+			a:    "github.com/Org/Repo?ref=v1",
+			b:    "github.com/org/repo?ref=v1",
+			want: false,
+		},
+	} {
+		a := test.ParseSource(t, tc.a)
+		b := test.ParseSource(t, tc.b)
+		if got := a.Equal(b); got != tc.want {
+			t.Errorf("%q.Equal(%q) = %t, want %t", tc.a, tc.b, got, tc.want)
+		}
+		if got := b.Equal(a); got != tc.want {
+			t.Errorf("%q.Equal(%q) = %t, want %t", tc.b, tc.a, got, tc.want)
+		}
+	}
+}
+
+func TestDedupSources(t *testing.T) {
+	t.Parallel()
+
+	var sources []tf.Source
+	for _, src := range []string{
+		"github.com/terramate-io/example?ref=v1",
+		"git@github.com:terramate-io/example.git?ref=v1",
+		"git::https://github.com/terramate-io/example.git?ref=v1",
+		"github.com/terramate-io/example.git?ref=refs/tags/v1",
+		"github.com/terramate-io/example?ref=v2",
+		"git@github.com:terramate-io/example?ref=v1",
+	} {
+		sources = append(sources, test.ParseSource(t, src))
+	}
+
+	got := tf.DedupSources(sources)
+	want := []tf.Source{sources[0], sources[1], sources[4]}
+	test.AssertDiff(t, got, want)
+	assert.EqualInts(t, 0, len(tf.DedupSources(nil)))
+}
+
+func TestGroupSourcesByHost(t *testing.T) {
+	t.Parallel()
+
+	var sources []tf.Source
+	for _, src := range []string{
+		"github.com/terramate-io/example?ref=v1",
+		"./modules/vpc",
+		"git::https://gitlab.acme.com/infra/vpc.git?ref=v1",
+		"git@github.com:terramate-io/other.git?ref=v2",
+		"hashicorp/consul/aws?version=1.0.0",
+		"../shared",
+		"ssh://git@gitlab.acme.com:2222/infra/dns.git?ref=v3",
+	} {
+		sources = append(sources, test.ParseSource(t, src))
+	}
+
+	groups := tf.GroupSourcesByHost(sources)
+	test.AssertDiff(t, tf.SortedHosts(groups), []string{
+		"", "github.com", "gitlab.acme.com", "registry.terraform.io",
+	})
+	test.AssertDiff(t, groups[""], []tf.Source{sources[1], sources[5]})
+	test.AssertDiff(t, groups["github.com"], []tf.Source{sources[0], sources[3]})
+	test.AssertDiff(t, groups["gitlab.acme.com"], []tf.Source{sources[2], sources[6]})
+	test.AssertDiff(t, groups["registry.terraform.io"], []tf.Source{sources[4]})
+
+	assert.EqualInts(t, 0, len(tf.GroupSourcesByHost(nil)))
+	assert.EqualInts(t, 0, len(tf.SortedHosts(nil)))
+}
+
+func TestSourceResolveLocal(t *testing.T) {
+	t.Parallel()
+
+	root := t.TempDir()
+	stackDir := filepath.Join(root, "stacks", "vpc")
+
+	type testcase struct {
+		source string
+		want   string
+		err    error
+	}
+
+	for _, tc := range []testcase{
+		{
+			source: "./modules/vpc",
+			want:   filepath.Join(stackDir, "modules", "vpc"),
+		},
+		{
+			source: "../../modules//vpc",
+			want:   filepath.Join(root, "modules", "vpc"),
+		},
+		{
+			source: "../..",
+			want:   root,
+		},
+		{
+			source: filepath.ToSlash(filepath.Join(root, "modules", "abs")),
+			want:   filepath.Join(root, "modules", "abs"),
+		},
+		{
+			source: "../../../outside",
+			err:    errors.E(tf.ErrModSrcOutsideRoot),
+		},
+		{
+			source: "../../../" + filepath.Base(root) + "-sibling/mod",
+			err:    errors.E(tf.ErrModSrcOutsideRoot),
+		},
+		{
+			source: "github.com/terramate-io/example",
+			err:    errors.E(tf.ErrUnsupportedModSrc),
+		},
+		{
+			source: "hashicorp/consul/aws",
+			err:    errors.E(tf.ErrUnsupportedModSrc),
+		},
+	} {
+		src := test.ParseSource(t, tc.source)
+		got, err := src.ResolveLocal(root, stackDir)
+		assert.IsError(t, err, tc.err, "resolving %q", tc.source)
+		assert.EqualStrings(t, tc.want, got, "resolving %q", tc.source)
+	}
+}
+
+func TestLintLocalSource(t *testing.T) {
+	t.Parallel()
+
+	root := t.TempDir()
+	stackDir := filepath.Join(root, "stacks", "vpc")
+
+	for _, tc := range []struct {
+		source string
+		err    error
+	}{
+		{source: "./modules/vpc"},
+		{source: "../../modules/vpc"},
+		{source: "../.."},
+		{source: "../../stacks/../modules"},
+		{source: filepath.ToSlash(root)},
+		{source: "../../..", err: errors.E(tf.ErrModuleEscapesRoot)},
+		{source: "../../../../modules/vpc", err: errors.E(tf.ErrModuleEscapesRoot)},
+		{source: "../../../" + filepath.Base(root) + "-other", err: errors.E(tf.ErrModuleEscapesRoot)},
+		{source: filepath.ToSlash(filepath.Dir(root)), err: errors.E(tf.ErrModuleEscapesRoot)},
+		{source: "github.com/terramate-io/example?ref=v1"},
+		{source: "hashicorp/consul/aws"},
+	} {
+		err := tf.LintLocalSource(test.ParseSource(t, tc.source), stackDir, root)
+		assert.IsError(t, err, tc.err, "linting %q", tc.source)
+	}
+
+	// lint failures wrap the resolve failure, which has its own kind.
+	src := test.ParseSource(t, "../../..")
+	lintErr := tf.LintLocalSource(src, stackDir, root)
+	assert.IsError(t, lintErr, errors.E(tf.ErrModSrcOutsideRoot))
+	_, resolveErr := src.ResolveLocal(root, stackDir)
+	assert.IsError(t, resolveErr, errors.E(tf.ErrModSrcOutsideRoot))
+	assert.IsTrue(t, !errors.IsKind(resolveErr, tf.ErrModuleEscapesRoot),
+		"resolve error %v must not be a lint error", resolveErr)
+}
+
+func TestSourceSubdirPath(t *testing.T) {
+	t.Parallel()
+
+	type testcase struct {
+		source     string
+		packageDir string
+		want       string
+	}
+
+	for _, tc := range []testcase{
+		{
+			source:     "github.com/terramate-io/example",
+			packageDir: "/vendor/example",
+			want:       filepath.FromSlash("/vendor/example"),
+		},
+		{
+			source:     "github.com/terramate-io/example",
+			packageDir: "",
+			want:       ".",
+		},
+		{
+			source:     "github.com/terramate-io/example//vpc",
+			packageDir: "/vendor/example/",
+			want:       filepath.FromSlash("/vendor/example/vpc"),
+		},
+		{
+			source:     "git::https://example.com/vpc.git//modules/net/private?ref=v1",
+			packageDir: "/vendor/example",
+			want:       filepath.FromSlash("/vendor/example/modules/net/private"),
+		},
+		{
+			source:     "github.com/terramate-io/example//modules/vpc",
+			packageDir: "vendor/example",
+			want:       filepath.FromSlash("vendor/example/modules/vpc"),
+		},
+		{
+			source:     "github.com/terramate-io/example//modules/./vpc/",
+			packageDir: "",
+			want:       filepath.FromSlash("modules/vpc"),
+		},
+		{
+			source:     "github.com/terramate-io/example//modules/../vpc",
+			packageDir: "/vendor/example",
+			want:       filepath.FromSlash("/vendor/example/vpc"),
+		},
+	} {
+		src := test.ParseSource(t, tc.source)
+		assert.EqualStrings(t, tc.want, src.SubdirPath(tc.packageDir),
+			"SubdirPath(%q) of %q", tc.packageDir, tc.source)
+	}
+
+	// subdirs escaping the package can't have a SubdirPath.
+	_, err := tf.ParseSource("github.com/terramate-io/example//../../etc")
+	assert.IsError(t, err, errors.E(tf.ErrInvalidModSrc))
+}
+
+func TestSourceValidate(t *testing.T) {
+	t.Parallel()
+
+	type testcase struct {
+		name string
+		src  tf.Source
+		want error
+	}
+
+	for _, tc := range []testcase{
+		{
+			name: "valid git source",
+			src: tf.Source{
+				URL:    "https://github.com/terramate-io/example.git",
+				Path:   "github.com/terramate-io/example",
+				Subdir: "/vpc",
+				Ref:    "v1",
+			},
+		},
+		{
+			name: "valid local source",
+			src:  tf.Source{Path: "../modules/vpc", Local: true},
+		},
+		{
+			name: "valid registry source",
+			src: tf.Source{
+				Path:     "registry.terraform.io/hashicorp/consul/aws",
+				Registry: true,
+			},
+		},
+		{
+			name: "empty path",
+			src:  tf.Source{URL: "https://github.com/terramate-io/example.git"},
+			want: errors.E(tf.ErrInvalidModSrc),
+		},
+		{
+			name: "subdir without leading slash",
+			src: tf.Source{
+				URL:    "https://github.com/terramate-io/example.git",
+				Path:   "github.com/terramate-io/example",
+				Subdir: "vpc",
+			},
+			want: errors.E(tf.ErrInvalidModSrc),
+		},
+		{
+			name: "ref with whitespace",
+			src: tf.Source{
+				URL:  "https://github.com/terramate-io/example.git",
+				Path: "github.com/terramate-io/example",
+				Ref:  "v1 ",
+			},
+			want: errors.E(tf.ErrInvalidModSrc),
+		},
+		{
+			name: "remote source without url",
+			src:  tf.Source{Path: "github.com/terramate-io/example"},
+			want: errors.E(tf.ErrInvalidModSrc),
+		},
+		{
+			name: "subdir escaping the package root",
+			src: tf.Source{
+				URL:    "https://github.com/terramate-io/example.git",
+				Path:   "github.com/terramate-io/example",
+				Subdir: "/a/../../etc",
+			},
+			want: errors.E(tf.ErrInvalidModSrc),
+		},
+		{
+			name: "subdir with .. inside the package root",
+			src: tf.Source{
+				URL:    "https://github.com/terramate-io/example.git",
+				Path:   "github.com/terramate-io/example",
+				Subdir: "/a/../b/..",
+			},
+		},
+		{
+			name: "scp source without repository path",
+			src: tf.Source{
+				URL:        "git@example.com:",
+				Path:       "example.com",
+				Host:       "example.com",
+				PathScheme: "git",
+			},
+			want: errors.E(tf.ErrInvalidModSrc),
+		},
+		{
+			name: "https source with only .git as repository path",
+			src: tf.Source{
+				URL:        "https://example.com/.git",
+				Path:       "example.com/",
+				Host:       "example.com",
+				PathScheme: "https",
+			},
+			want: errors.E(tf.ErrInvalidModSrc),
+		},
+	} {
+		assert.IsError(t, tc.src.Validate(), tc.want, tc.name)
+	}
+
+	_, err := tf.ParseSource("github.com/terramate-io/example?ref=v1+2")
+	assert.IsError(t, err, errors.E(tf.ErrInvalidModSrc))
+}
+
+func TestSourceSlug(t *testing.T) {
+	t.Parallel()
+
+	for _, tc := range []struct {
+		source string
+		want   string
+	}{
+		{source: "github.com/acme/infra?ref=v1", want: "acme/infra"},
+		{source: "github.com/acme/infra//modules/vpc?ref=v1", want: "acme/infra"},
+		{source: "git@github.com:acme/infra.git", want: "acme/infra"},
+		{source: "gitlab.com/group/subgroup/infra?ref=v1", want: "group/subgroup/infra"},
+		{source: "git::ssh://git@gitlab.acme.com:2222/group/sub/infra.git", want: "group/sub/infra"},
+		{source: "https://example.com/modules/vpc.zip", want: "modules/vpc.zip"},
+		{source: "hashicorp/consul/aws//modules/consul-cluster", want: "hashicorp/consul"},
+		{source: "app.terraform.io/example-corp/k8s-cluster/azurerm@1.0.0", want: "example-corp/k8s-cluster"},
+		{source: "./modules//vpc/", want: "./modules/vpc"},
+		{source: "../shared", want: "../shared"},
+		{source: "git::file:///opt/mirror/infra.git", want: "opt/mirror/infra"},
+	} {
+		got := test.ParseSource(t, tc.source).Slug()
+		assert.EqualStrings(t, tc.want, got, "Slug() of %q", tc.source)
+	}
+}
+
+func TestSourceRepoIdentity(t *testing.T) {
+	t.Parallel()
+
+	github := test.ParseSource(t, "github.com/acme/infra//modules/vpc?ref=v1")
+	mirror := test.ParseSource(t, "git::https://git.acme.internal/acme/infra.git?ref=v2")
+	scp := test.ParseSource(t, "git@gitlab.com:acme/infra.git")
+
+	assert.EqualStrings(t, "acme/infra", github.RepoIdentity())
+	assert.EqualStrings(t, github.RepoIdentity(), mirror.RepoIdentity())
+	assert.EqualStrings(t, github.RepoIdentity(), scp.RepoIdentity())
+	assert.IsTrue(t, !github.SamePackage(mirror), "mirrors are different packages")
+
+	other := test.ParseSource(t, "github.com/acme/other")
+	assert.IsTrue(t, other.RepoIdentity() != github.RepoIdentity())
+	assert.EqualStrings(t, "group/subgroup/infra", test.ParseSource(t, "gitlab.com/group/subgroup/infra").RepoIdentity())
+	assert.EqualStrings(t, "hashicorp/consul", test.ParseSource(t, "hashicorp/consul/aws?version=1.0.0").RepoIdentity())
+	assert.EqualStrings(t, "", test.ParseSource(t, "./modules/vpc").RepoIdentity())
+}
+
+func TestSourcePackageDir(t *testing.T) {
+	t.Parallel()
+
+	type testcase struct {
+		source      string
+		wantDir     string
+		wantWithRef string
+	}
+
+	for _, tc := range []testcase{
+		{
+			source:      "github.com/terramate-io/example",
+			wantDir:     "github.com_terramate-io_example",
+			wantWithRef: "github.com_terramate-io_example",
+		},
+		{
+			source:      "github.com/terramate-io/example.git//modules/vpc?ref=v1",
+			wantDir:     "github.com_terramate-io_example",
+			wantWithRef: "github.com_terramate-io_example@v1",
+		},
+		{
+			source:      "git::https://example.com/vpc.git?ref=refs/heads/feature/x",
+			wantDir:     "example.com_vpc",
+			wantWithRef: "example.com_vpc@feature_x",
+		},
+		{
+			source:      "git::ssh://git@example.com:2222/org/vpc.git//sub",
+			wantDir:     "example.com_org_vpc",
+			wantWithRef: "example.com_org_vpc",
+		},
+		{
+			source:      "git@github.com:terramate-io/example.git//other?ref=v2",
+			wantDir:     "github.com_terramate-io_example",
+			wantWithRef: "github.com_terramate-io_example@v2",
+		},
+	} {
+		src := test.ParseSource(t, tc.source)
+		assert.EqualStrings(t, tc.wantDir, src.PackageDir(), "PackageDir() of %q", tc.source)
+		assert.EqualStrings(t, tc.wantWithRef, src.PackageDirWithRef(),
+			"PackageDirWithRef() of %q", tc.source)
+	}
+}
+
+func TestSourceCloneSpec(t *testing.T) {
+	t.Parallel()
+
+	const sha = "4e991b55e3d58b9c3137a791a9986ed9c5069697"
+
+	type testcase struct {
+		source string
+		want   tf.CloneSpec
+		args   []string
+	}
+
+	for _, tc := range []testcase{
+		{
+			source: "github.com/terramate-io/example//vpc?ref=v1.0.0",
+			want: tf.CloneSpec{
+				URL:    "https://github.com/terramate-io/example.git",
+				Ref:    "v1.0.0",
+				Subdir: "/vpc",
+			},
+			args: []string{"clone", "--branch", "v1.0.0",
+				"https://github.com/terramate-io/example.git", "dir"},
+		},
+		{
+			source: "git::https://example.com/vpc.git?ref=refs/heads/main&depth=1",
+			want: tf.CloneSpec{
+				URL:   "https://example.com/vpc.git",
+				Ref:   "refs/heads/main",
+				Depth: 1,
+			},
+			args: []string{"clone", "--branch", "main", "--depth", "1",
+				"https://example.com/vpc.git", "dir"},
+		},
+		{
+			source: "git::ssh://git@example.com/vpc.git?depth=10",
+			want: tf.CloneSpec{
+				URL:   "ssh://git@example.com/vpc.git",
+				Depth: 10,
+			},
+			args: []string{"clone", "--depth", "10", "ssh://git@example.com/vpc.git", "dir"},
+		},
+		{
+			source: "git@github.com:terramate-io/example.git//modules/a?ref=" + sha + "&depth=1",
+			want: tf.CloneSpec{
+				URL:      "git@github.com:terramate-io/example.git",
+				Ref:      sha,
+				Depth:    1,
+				Subdir:   "/modules/a",
+				Checkout: true,
+			},
+			args: []string{"clone", "git@github.com:terramate-io/example.git", "dir"},
+		},
+		{
+			source: "git::https://example.com/vpc.git?depth=invalid",
+			want: tf.CloneSpec{
+				URL: "https://example.com/vpc.git",
+			},
+			args: []string{"clone", "https://example.com/vpc.git", "dir"},
+		},
+	} {
+		src := test.ParseSource(t, tc.source)
+		got := src.CloneSpec()
+		if got != tc.want {
+			t.Fatalf("CloneSpec() of %q = %+v, want %+v", tc.source, got, tc.want)
+		}
+		assert.EqualStrings(t, strings.Join(tc.args, " "), strings.Join(got.Args("dir"), " "),
+			"Args() of %q", tc.source)
+	}
+
+	for _, source := range []string{
+		"../modules/vpc",
+		"hashicorp/consul/aws?version=1.0.0",
+		"https://example.com/vpc.zip",
+	} {
+		src := test.ParseSource(t, source)
+		if got := src.CloneSpec(); got != (tf.CloneSpec{}) {
+			t.Fatalf("CloneSpec() of %q = %+v, want zero value", source, got)
+		}
+	}
+}
+
+func TestSourceRedacted(t *testing.T) {
+	t.Parallel()
+
+	const token = "s3cr3t-t0k3n"
+
+	type testcase struct {
+		source string
+		path   string
+		want   string
+	}
+
+	for _, tc := range []testcase{
+		{
+			source: "git::https://oauth2:" + token + "@gitlab.acme.com/infra/modules.git//vpc?ref=v1",
+			path:   "gitlab.acme.com/infra/modules",
+			want:   "git::https://gitlab.acme.com/infra/modules.git//vpc?ref=v1",
+		},
+		{
+			source: "git::https://" + token + "@github.com/terramate-io/example.git?ref=v1",
+			path:   "github.com/terramate-io/example",
+			want:   "github.com/terramate-io/example?ref=v1",
+		},
+		{
+			source: "git::ssh://git:" + token + "@example.com:2222/org/vpc.git",
+			path:   "example.com/org/vpc",
+			want:   "git::ssh://git@example.com:2222/org/vpc.git",
+		},
+		{
+			source: "git::ssh://git@example.com/org/vpc.git?sshkey=" + token + "&ref=v1",
+			path:   "example.com/org/vpc",
+			want:   "git::ssh://git@example.com/org/vpc.git?ref=v1",
+		},
+		{
+			source: "git@github.com:terramate-io/example.git?ref=v1",
+			path:   "github.com/terramate-io/example",
+			want:   "git@github.com:terramate-io/example.git?ref=v1",
+		},
+		{
+			source: "https://user:" + token + "@example.com/modules/vpc.zip",
+			path:   "example.com/modules/vpc.zip",
+			want:   "https://example.com/modules/vpc.zip",
+		},
+	} {
+		src := test.ParseSource(t, tc.source)
+		assert.EqualStrings(t, tc.path, src.Path, "Path of %q", tc.source)
+		assert.EqualStrings(t, tc.want, src.Redacted(), "Redacted() of %q", tc.source)
+		assert.IsTrue(t, !strings.Contains(src.Path, token), "token leaked into Path %q", src.Path)
+		assert.IsTrue(t, !strings.Contains(src.Redacted(), token),
+			"token leaked into Redacted() %q", src.Redacted())
+	}
+}
+
+func TestReplaceRef(t *testing.T) {
+	t.Parallel()
+
+	type testcase struct {
+		source string
+		ref    string
+		want   string
+		err    error
+	}
+
+	for _, tc := range []testcase{
+		{
+			source: "github.com/terramate-io/example//vpc?ref=v1",
+			ref:    "v2",
+			want:   "github.com/terramate-io/example//vpc?ref=v2",
+		},
+		{
+			source: "github.com/terramate-io/example.git",
+			ref:    "v2",
+			want:   "github.com/terramate-io/example.git?ref=v2",
+		},
+		{
+			source: "github.com/terramate-io/example?ref=v1//vpc&depth=1",
+			ref:    "refs/tags/v2",
+			want:   "github.com/terramate-io/example?ref=refs/tags/v2//vpc&depth=1",
+		},
+		{
+			source: "git::https://example.com/vpc.git?depth=1&ref=v1&sshkey=abc",
+			ref:    "v2",
+			want:   "git::https://example.com/vpc.git?depth=1&ref=v2&sshkey=abc",
+		},
+		{
+			source: "git::ssh://git@example.com/vpc.git?sshkey=ab//cd&ref=v1//vpc",
+			ref:    "v2",
+			want:   "git::ssh://git@example.com/vpc.git?sshkey=ab//cd&ref=v2//vpc",
+		},
+		{
+			source: "git::https://example.com/vpc.git?ref=a&depth=1&ref=b",
+			ref:    "x",
+			want:   "git::https://example.com/vpc.git?ref=x&depth=1",
+		},
+		{
+			source: "github.com/terramate-io/example?depth=1&ref=a&ref=b//vpc",
+			ref:    "x",
+			want:   "github.com/terramate-io/example?depth=1&ref=x//vpc",
+		},
+		{
+			source: "git::https://example.com/vpc.git//modules?depth=1",
+			ref:    "v2",
+			want:   "git::https://example.com/vpc.git//modules?depth=1&ref=v2",
+		},
+		{
+			source: "git@github.com:terramate-io/example.git//sub?ref=v1",
+			ref:    "4e991b55e3d58b9c3137a791a9986ed9c5069697",
+			want:   "git@github.com:terramate-io/example.git//sub?ref=4e991b55e3d58b9c3137a791a9986ed9c5069697",
+		},
+		{
+			source: "git@github.com:terramate-io/example.git",
+			ref:    "feature&x",
+			want:   "git@github.com:terramate-io/example.git?ref=feature%26x",
+		},
+		{
+			source: "github.com/terramate-io/example?ref=v1",
+			ref:    "",
+			err:    errors.E(tf.ErrInvalidModSrc),
+		},
+		{
+			source: "../modules/vpc",
+			ref:    "v2",
+			err:    errors.E(tf.ErrUnsupportedModSrc),
+		},
+		{
+			source: "hashicorp/consul/aws",
+			ref:    "v2",
+			err:    errors.E(tf.ErrUnsupportedModSrc),
+		},
+	} {
+		got, err := tf.ReplaceRef(tc.source, tc.ref)
+		assert.IsError(t, err, tc.err, "ReplaceRef(%q, %q)", tc.source, tc.ref)
+		assert.EqualStrings(t, tc.want, got, "ReplaceRef(%q, %q)", tc.source, tc.ref)
+		if tc.err != nil {
+			continue
+		}
+
+		src := test.ParseSource(t, tc.source)
+		withRef, err := src.WithRef(tc.ref)
+		assert.NoError(t, err)
+		assert.EqualStrings(t, tc.ref, withRef.Ref)
+		assert.EqualStrings(t, tc.want, withRef.Raw)
+		assert.IsTrue(t, withRef.Equal(test.ParseSource(t, got)),
+			"WithRef(%q) of %q must be equal to %q", tc.ref, tc.source, got)
+		assert.EqualStrings(t, src.Raw, tc.source, "WithRef must not change the original source")
+	}
+}
+
+func TestSourceWithRef(t *testing.T) {
+	t.Parallel()
+
+	for _, tc := range []struct {
+		source string
+		ref    string
+		err    error
+	}{
+		{source: "hashicorp/consul/aws", ref: "v1", err: errors.E(tf.ErrUnsupportedModSrc)},
+		{source: "../modules/vpc", ref: "v1", err: errors.E(tf.ErrUnsupportedModSrc)},
+		{source: "https://example.com/vpc-module.zip", ref: "v1", err: errors.E(tf.ErrUnsupportedModSrc)},
+		{source: "github.com/terramate-io/example?ref=v1", ref: "", err: errors.E(tf.ErrInvalidModSrc)},
+		{source: "github.com/terramate-io/example?ref=v1", ref: "v 2", err: errors.E(tf.ErrInvalidModSrc)},
+	} {
+		src := test.ParseSource(t, tc.source)
+		_, err := src.WithRef(tc.ref)
+		assert.IsError(t, err, tc.err, "WithRef(%q) of %q", tc.ref, tc.source)
+	}
+
+	// hand built sources have no Raw to rewrite.
+	src := tf.Source{
+		URL:        "https://example.com/vpc.git",
+		Path:       "example.com/vpc",
+		Host:       "example.com",
+		PathScheme: "https",
+	}
+	got, err := src.WithRef("v2")
+	assert.NoError(t, err)
+	assert.EqualStrings(t, "v2", got.Ref)
+	assert.EqualStrings(t, "", got.Raw)
+	assert.EqualStrings(t, "git::https://example.com/vpc.git?ref=v2", got.String())
+}
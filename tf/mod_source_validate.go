@@ -0,0 +1,97 @@
+// Copyright 2024 Terramate GmbH
+// SPDX-License-Identifier: MPL-2.0
+
+package tf
+
+import (
+	"fmt"
+	"sort"
+)
+
+// Severity of a [Diagnostic].
+type Severity int
+
+const (
+	// SeverityError indicates that the module source is not valid.
+	SeverityError Severity = iota
+	// SeverityWarning indicates that the module source is valid but it may
+	// lead to unexpected results, eg.: a git source not pinned to a ref.
+	SeverityWarning
+)
+
+// Diagnostic is a problem found in a module source by [ValidateSources].
+type Diagnostic struct {
+	// Label is the location label of the module source.
+	Label string
+	// Message describes the problem.
+	Message string
+	// Severity of the problem.
+	Severity Severity
+}
+
+// Diagnostics is a list of [Diagnostic].
+type Diagnostics []Diagnostic
+
+// String returns the name of the severity.
+func (s Severity) String() string {
+	switch s {
+	case SeverityError:
+		return "error"
+	case SeverityWarning:
+		return "warning"
+	}
+	return fmt.Sprintf("Severity(%d)", int(s))
+}
+
+// String returns the diagnostic formatted as "label: severity: message".
+func (d Diagnostic) String() string {
+	return fmt.Sprintf("%s: %s: %s", d.Label, d.Severity, d.Message)
+}
+
+// HasErrors tells if any of the diagnostics is a [SeverityError].
+func (diags Diagnostics) HasErrors() bool {
+	for _, d := range diags {
+		if d.Severity == SeverityError {
+			return true
+		}
+	}
+	return false
+}
+
+// ValidateSources parses and validates all the module sources, which are
+// keyed by a location label, returning the problems of all of them instead
+// of stopping at the first one. The diagnostics are sorted by label.
+// Git sources without a ref are reported as warnings.
+func ValidateSources(sources map[string]string) Diagnostics {
+	labels := make([]string, 0, len(sources))
+	for label := range sources {
+		labels = append(labels, label)
+	}
+	sort.Strings(labels)
+
+	var diags Diagnostics
+	for _, label := range labels {
+		diags = append(diags, validateSource(label, sources[label])...)
+	}
+	return diags
+}
+
+func validateSource(label, modsource string) Diagnostics {
+	// ParseSource already checks the result with Source.Validate.
+	src, err := ParseSource(modsource)
+	if err != nil {
+		return Diagnostics{{
+			Label:    label,
+			Message:  err.Error(),
+			Severity: SeverityError,
+		}}
+	}
+	if src.Ref == "" && !src.Local && !src.Registry && !src.Archive {
+		return Diagnostics{{
+			Label:    label,
+			Message:  fmt.Sprintf("module source %q is not pinned to a ref", modsource),
+			Severity: SeverityWarning,
+		}}
+	}
+	return nil
+}
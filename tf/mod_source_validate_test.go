@@ -0,0 +1,57 @@
+// Copyright 2024 Terramate GmbH
+// SPDX-License-Identifier: MPL-2.0
+
+package tf_test
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/madlambda/spells/assert"
+	"github.com/terramate-io/terramate/tf"
+)
+
+func TestValidateSources(t *testing.T) {
+	t.Parallel()
+
+	diags := tf.ValidateSources(map[string]string{
+		"stacks/a/main.tf:1": "github.com/terramate-io/example?ref=v1.0.0",
+		"stacks/a/main.tf:5": "../modules/vpc",
+		"stacks/b/main.tf:1": "hashicorp/consul/aws//modules/consul-cluster?version=0.1.0",
+		"stacks/b/main.tf:9": "git::https://example.com/vpc.git",
+		"stacks/c/main.tf:1": "gcs::https://www.googleapis.com/storage/v1/modules/foomodule.zip",
+		"stacks/c/main.tf:3": "git::https://example.com/vpc.git?ref=v1//",
+		"stacks/d/main.tf:1": "",
+		"stacks/d/main.tf:2": "git::%%%",
+		"stacks/d/main.tf:3": "github.com/org/repo?ref=v1 x",
+	})
+
+	type diag struct {
+		Label    string
+		Severity tf.Severity
+	}
+	var got []diag
+	for _, d := range diags {
+		if d.Message == "" {
+			t.Errorf("diagnostic %s has no message", d.Label)
+		}
+		got = append(got, diag{Label: d.Label, Severity: d.Severity})
+	}
+
+	want := []diag{
+		{Label: "stacks/b/main.tf:9", Severity: tf.SeverityWarning},
+		{Label: "stacks/c/main.tf:1", Severity: tf.SeverityError},
+		{Label: "stacks/c/main.tf:3", Severity: tf.SeverityError},
+		{Label: "stacks/d/main.tf:1", Severity: tf.SeverityError},
+		{Label: "stacks/d/main.tf:2", Severity: tf.SeverityError},
+		{Label: "stacks/d/main.tf:3", Severity: tf.SeverityError},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Fatalf("ValidateSources() mismatch (-want +got):\n%s", diff)
+	}
+	assert.IsTrue(t, diags.HasErrors())
+	assert.IsTrue(t, !tf.ValidateSources(map[string]string{
+		"main.tf:1": "git::https://example.com/vpc.git",
+	}).HasErrors())
+	assert.EqualInts(t, 0, len(tf.ValidateSources(nil)))
+}
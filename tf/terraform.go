@@ -91,6 +91,56 @@ func ParseModules(path string) ([]Module, error) {
 	return modules, nil
 }
 
+// ModuleSource is a module source parsed by [ParseSources].
+type ModuleSource struct {
+	// Label is the label of the module block defining the source.
+	Label string
+
+	Source
+}
+
+// ParseSources parses the source attribute of every module block, containing
+// a single label, of the HCL content. Unlike [ParseModules], invalid sources
+// don't stop the parsing and an error, annotated with the module label, is
+// collected for each of them. Module blocks without a source are ignored.
+func ParseSources(content []byte) ([]ModuleSource, []error) {
+	p := hclparse.NewParser()
+	f, diags := p.ParseHCL(content, "")
+	if diags.HasErrors() {
+		return nil, []error{errors.E(ErrHCLSyntax, diags)}
+	}
+
+	body := f.Body.(*hclsyntax.Body)
+
+	var (
+		sources []ModuleSource
+		errs    []error
+	)
+	for _, block := range body.Blocks {
+		if block.Type != "module" || len(block.Labels) != 1 {
+			continue
+		}
+
+		label := block.Labels[0]
+		modsource, ok, err := findStringAttr(block, "source")
+		if err != nil {
+			errs = append(errs, errors.E(err, "module %q", label))
+			continue
+		}
+		if !ok {
+			continue
+		}
+
+		src, err := ParseSource(modsource)
+		if err != nil {
+			errs = append(errs, errors.E(err, "module %q", label))
+			continue
+		}
+		sources = append(sources, ModuleSource{Label: label, Source: src})
+	}
+	return sources, errs
+}
+
 // IsStack tells if the file defined by path is a potential stack.
 // Eg.: has a backend block or a provider block.
 func IsStack(path string) (bool, error) {
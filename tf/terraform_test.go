@@ -6,6 +6,7 @@ package tf_test
 import (
 	"fmt"
 	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/madlambda/spells/assert"
@@ -218,6 +219,71 @@ module "test" {
 	}
 }
 
+func TestParseSources(t *testing.T) {
+	t.Parallel()
+
+	sources, errs := tf.ParseSources([]byte(`
+		module "vpc" {
+			source = "github.com/terramate-io/example//vpc?ref=v1"
+		}
+
+		module "local" {
+			source = "../modules/local"
+		}
+
+		module "invalid" {
+			source = "github.com/terramate-io/example//"
+		}
+
+		module "unsupported" {
+			source = "s3::https://s3-eu-west-1.amazonaws.com/bucket/vpc.zip"
+		}
+
+		module "not_string" {
+			source = 1
+		}
+
+		module "no_source" {}
+
+		module "a" "b" {
+			source = "../ignored"
+		}
+
+		resource "null" "x" {}
+
+		module "consul" {
+			source = "hashicorp/consul/aws"
+		}
+	`))
+
+	type want struct {
+		label  string
+		source string
+	}
+	wantSources := []want{
+		{label: "vpc", source: "github.com/terramate-io/example//vpc?ref=v1"},
+		{label: "local", source: "../modules/local"},
+		{label: "consul", source: "hashicorp/consul/aws"},
+	}
+	assert.EqualInts(t, len(wantSources), len(sources), "got sources: %v", sources)
+	for i, w := range wantSources {
+		assert.EqualStrings(t, w.label, sources[i].Label)
+		assert.EqualStrings(t, w.source, sources[i].Raw)
+	}
+
+	assert.EqualInts(t, 3, len(errs), "got errs: %v", errs)
+	assert.IsError(t, errs[0], errors.E(tf.ErrInvalidModSrc))
+	assert.IsError(t, errs[1], errors.E(tf.ErrUnsupportedModSrc))
+	for i, label := range []string{"invalid", "unsupported", "not_string"} {
+		assert.IsTrue(t, strings.Contains(errs[i].Error(), fmt.Sprintf("%q", label)),
+			"error %v must mention module %q", errs[i], label)
+	}
+
+	_, errs = tf.ParseSources([]byte(`module "x" {`))
+	assert.EqualInts(t, 1, len(errs))
+	assert.IsError(t, errs[0], errors.E(tf.ErrHCLSyntax))
+}
+
 func TestTerraformHasBackend(t *testing.T) {
 	t.Parallel()
 